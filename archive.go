@@ -0,0 +1,319 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"sync"
+
+	"github.com/9r33n/wildcatting/game"
+	"github.com/gorilla/mux"
+)
+
+// recentGames is how many closed games the archive keeps in memory.
+const recentGames = 16
+
+// archive keeps closed games. The most recently closed or looked at stay in
+// memory. When it has a directory, their journals are saved there as JSON
+// files, and older games are restored from their journals when they're
+// looked at again. Without one, older games are forgotten. Async games
+// write their journals there as they're played, to be resumed from if the
+// server restarts.
+type archive struct {
+	dir string
+
+	sync.Mutex
+	recent map[int]game.Game
+	order  []int
+	// the journals of async games still in play
+	journals map[int]*os.File
+}
+
+func newArchive(dir string) *archive {
+	return &archive{dir: dir, recent: make(map[int]game.Game), journals: make(map[int]*os.File)}
+}
+
+// keep holds on to a closed game, forgetting the oldest once there are too
+// many.
+func (a *archive) keep(gameID int, g game.Game) {
+	a.Lock()
+	defer a.Unlock()
+	if _, ok := a.recent[gameID]; !ok {
+		a.order = append(a.order, gameID)
+	}
+	a.recent[gameID] = g
+	for len(a.order) > recentGames {
+		delete(a.recent, a.order[0])
+		a.order = a.order[1:]
+	}
+}
+
+func (a *archive) path(gameID int) string {
+	return filepath.Join(a.dir, fmt.Sprintf("game%d.json", gameID))
+}
+
+// has reports whether a closed game is saved in the archive's directory.
+func (a *archive) has(gameID int) bool {
+	_, err := os.Stat(a.path(gameID))
+	return err == nil
+}
+
+// journalPath is where an async game in play writes its journal.
+func (a *archive) journalPath(gameID int) string {
+	return filepath.Join(a.dir, fmt.Sprintf("game%d.journal", gameID))
+}
+
+// optionsPath is where an async game in play keeps the options it was
+// created with.
+func (a *archive) optionsPath(gameID int) string {
+	return filepath.Join(a.dir, fmt.Sprintf("game%d.options.json", gameID))
+}
+
+// Journal saves the options an async game is created with, and opens the
+// file its journal is written to as it's played. It returns nil without a
+// directory.
+func (a *archive) Journal(gameID int, opts gameOptions) (io.Writer, error) {
+	if a.dir == "" {
+		return nil, nil
+	}
+	js, err := json.Marshal(opts)
+	if err != nil {
+		return nil, err
+	}
+	if err := ioutil.WriteFile(a.optionsPath(gameID), js, 0644); err != nil {
+		return nil, err
+	}
+	f, err := os.OpenFile(a.journalPath(gameID), os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0644)
+	if err != nil {
+		return nil, err
+	}
+	a.Lock()
+	a.journals[gameID] = f
+	a.Unlock()
+	return f, nil
+}
+
+// closeJournal stops writing an async game's journal, and removes it once
+// the game is archived.
+func (a *archive) closeJournal(gameID int, archived bool) {
+	a.Lock()
+	f, ok := a.journals[gameID]
+	delete(a.journals, gameID)
+	a.Unlock()
+	if !ok {
+		return
+	}
+	if err := f.Close(); err != nil {
+		log.Printf("closing journal of game %d: %s", gameID, err)
+	}
+	if archived {
+		os.Remove(a.journalPath(gameID))
+		os.Remove(a.optionsPath(gameID))
+	}
+}
+
+// Put saves a closed game's journal.
+func (a *archive) Put(gameID int, g game.Game) error {
+	a.keep(gameID, g)
+	if a.dir == "" {
+		return nil
+	}
+	js, err := json.Marshal(g.Journal(0))
+	if err != nil {
+		return err
+	}
+	path := a.path(gameID)
+	if err := ioutil.WriteFile(path+".tmp", js, 0644); err != nil {
+		a.closeJournal(gameID, false)
+		return err
+	}
+	err = os.Rename(path+".tmp", path)
+	a.closeJournal(gameID, err == nil)
+	return err
+}
+
+// Get returns an archived game, restoring it from its journal if it's not
+// in memory.
+func (a *archive) Get(gameID int) (game.Game, error) {
+	a.Lock()
+	g, ok := a.recent[gameID]
+	a.Unlock()
+	if ok {
+		return g, nil
+	}
+	if a.dir == "" {
+		return nil, fmt.Errorf("game %d forgotten", gameID)
+	}
+
+	entries, err := a.entries(gameID)
+	if err != nil {
+		return nil, err
+	}
+	g, err = game.Restore(entries)
+	if err != nil {
+		return nil, err
+	}
+	a.keep(gameID, g)
+	return g, nil
+}
+
+// entries reads a game's journal, from the archive or, for a game that
+// couldn't be resumed, from where it was written as it was played.
+func (a *archive) entries(gameID int) ([]game.Entry, error) {
+	js, err := ioutil.ReadFile(a.path(gameID))
+	if os.IsNotExist(err) {
+		f, err := os.Open(a.journalPath(gameID))
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+		return game.ReadJournal(f)
+	}
+	if err != nil {
+		return nil, err
+	}
+	var entries []game.Entry
+	if err := json.Unmarshal(js, &entries); err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
+// archived matches the names of the files games are kept in.
+var archived = regexp.MustCompile(`^game([0-9]+)\.(json|journal)$`)
+
+// resume picks up where the server left off when it has an archive
+// directory. Archived games keep their IDs, and async games that were in
+// play carry on.
+func (h *handler) resume() error {
+	if h.archive.dir == "" {
+		return nil
+	}
+	files, err := ioutil.ReadDir(h.archive.dir)
+	if err != nil {
+		return err
+	}
+	var playing []int
+	for _, f := range files {
+		m := archived.FindStringSubmatch(f.Name())
+		if m == nil {
+			continue
+		}
+		gameID, _ := strconv.Atoi(m[1])
+		for len(h.games) <= gameID {
+			h.games = append(h.games, nil)
+		}
+		// a game archived just as the server stopped may have left its
+		// journal behind
+		if m[2] == "journal" && !h.archive.has(gameID) {
+			playing = append(playing, gameID)
+		}
+	}
+	for _, gameID := range playing {
+		g, err := h.archive.resume(gameID, h.options)
+		if err != nil {
+			log.Printf("resuming game %d: %s", gameID, err)
+			continue
+		}
+		h.games[gameID] = g
+		go h.putAway(gameID, g)
+		log.Println("Resumed game", gameID)
+	}
+	return nil
+}
+
+// resume carries on an async game from the journal it was writing.
+func (a *archive) resume(gameID int, options func(gameOptions) ([]game.Option, error)) (game.Game, error) {
+	js, err := ioutil.ReadFile(a.optionsPath(gameID))
+	if err != nil {
+		return nil, err
+	}
+	var opts gameOptions
+	if err := json.Unmarshal(js, &opts); err != nil {
+		return nil, err
+	}
+	// the field is in the journal, and its map may be gone
+	opts.Map = ""
+	gameOpts, err := options(opts)
+	if err != nil {
+		return nil, err
+	}
+	entries, err := a.entries(gameID)
+	if err != nil {
+		return nil, err
+	}
+
+	f, err := os.OpenFile(a.journalPath(gameID), os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, err
+	}
+	g, err := game.Resume(entries, append(gameOpts, game.WithJournal(f))...)
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	a.Lock()
+	a.journals[gameID] = f
+	a.Unlock()
+	return g, nil
+}
+
+// game returns a game, restoring it from the archive if it's been put away.
+func (h *handler) game(gameID int) (game.Game, bool) {
+	h.RLock()
+	if gameID >= len(h.games) {
+		h.RUnlock()
+		return nil, false
+	}
+	g := h.games[gameID]
+	h.RUnlock()
+	if g != nil {
+		return g, true
+	}
+
+	g, err := h.archive.Get(gameID)
+	if err != nil {
+		log.Printf("restoring game %d: %s", gameID, err)
+		return nil, false
+	}
+	return g, true
+}
+
+// putAway archives a game once it's closed, whether by an admin or because
+// its players abandoned it.
+func (h *handler) putAway(gameID int, g game.Game) {
+	<-g.Done()
+	g.Close()
+	if err := h.archive.Put(gameID, g); err != nil {
+		log.Printf("archiving game %d: %s", gameID, err)
+		return
+	}
+	h.Lock()
+	h.games[gameID] = nil
+	h.Unlock()
+	stats.Add("Archived", 1)
+	log.Printf("Archived game %d", gameID)
+}
+
+// close a game
+func (h *handler) deleteGameID(w http.ResponseWriter, r *http.Request) {
+	gameID, err := strconv.Atoi(mux.Vars(r)["gid"])
+	if err != nil {
+		// mux should guarantee a parsable int
+		panic(err)
+	}
+	g, ok := h.game(gameID)
+	if !ok {
+		http.Error(w, "game not found", http.StatusNotFound)
+		return
+	}
+	g.Close()
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -5,9 +5,12 @@ var player = 0;
 var probColor = d3.scale.quantize().domain([1, 100]).range(["#4575b4","#91bfdb","#e0f3f8","#fee090","#fc8d59","#d73027"]);
 var costColor = d3.scale.quantize().domain([10, 250]).range(["#4575b4","#91bfdb","#e0f3f8","#fee090","#fc8d59","#d73027"]);
 var taxColor = d3.scale.quantize().domain([100, 550]).range(["#4575b4","#91bfdb","#e0f3f8","#fee090","#fc8d59","#d73027"]);
-var oilColor = d3.scale.quantize().domain([1, 9]).range(["#4d4d4d",,"#878787","#bababa","#e0e0e0","#ffffff","#fddbc7","#f4a582","#d6604d","#b2182b"]);
+// open, river, road, pipeline, town
+var surfaceColor = d3.scale.ordinal().domain([0, 1, 2, 3, 4]).range(["#1a1a1a","#4575b4","#bababa","#fc8d59","#d73027"]);
+var oilColor = d3.scale.quantize().domain([1, 9]).range(["#4d4d4d","#878787","#bababa","#e0e0e0","#ffffff","#fddbc7","#f4a582","#d6604d","#b2182b"]);
 
 var state = {};
+var field = {height: 24, width: 80, topology: {grid: "square4"}};
 
 var fsm = StateMachine.create({
     initial: 'lobby',
@@ -23,6 +26,27 @@ var fsm = StateMachine.create({
         { name: 'report', from: 'lobby', to: 'report' },
         { name: 'drill', from: 'lobby', to: 'drill' },
         { name: 'wells', from: 'lobby', to: 'wells' },
+        { name: 'reenter', from: 'survey', to: 'reenter' },
+        { name: 'workover', from: 'survey', to: 'workover' },
+        { name: 'yes', from: 'reenter', to: 'drill' },
+        { name: 'yes', from: 'workover', to: 'wells' },
+        { name: 'no', from: ['reenter', 'workover'], to: 'survey' },
+        { name: 'complete', from: 'drill', to: 'complete' },
+        { name: 'yes', from: 'complete', to: 'wells' },
+        { name: 'no', from: 'complete', to: 'drill' },
+        { name: 'done', from: 'complete', to: 'wells' },
+        { name: 'connect', from: 'complete', to: 'connect' },
+        { name: 'yes', from: 'connect', to: 'wells' },
+        { name: 'no', from: 'connect', to: 'wells' },
+        { name: 'wait', from: 'lobby', to: 'wait' },
+        { name: 'survey', from: 'wait', to: 'survey' },
+        { name: 'summary', from: 'lobby', to: 'summary' },
+        { name: 'survey', from: 'summary', to: 'survey' },
+        { name: 'wait', from: 'summary', to: 'wait' },
+        // real-time players go between surveying and their wells as the
+        // weeks roll on
+        { name: 'wells', from: 'survey', to: 'wells' },
+        { name: 'survey', from: 'wells', to: 'survey' },
     ],
 
     callbacks: {
@@ -32,6 +56,12 @@ var fsm = StateMachine.create({
         onenterreport: report,
         onenterdrill: drill,
         onenterwells: wells,
+        onenterwait: wait,
+        onenterreenter: reenter,
+        onentercomplete: complete,
+        onenterworkover: workover,
+        onenterconnect: connect,
+        onentersummary: summary,
 
         onleavelobby: function() {
             d3.select("#lobby").style("display", "none");
@@ -50,9 +80,34 @@ var fsm = StateMachine.create({
             d3.select("#drill").style("display", "none");
             Mousetrap.reset();
         },
+        onleavecomplete: function() {
+            d3.select("#complete").style("display", "none");
+            Mousetrap.reset();
+        },
+        onleavereenter: function() {
+            d3.select("#reenter").style("display", "none");
+            Mousetrap.reset();
+        },
+        onleaveworkover: function() {
+            d3.select("#workover").style("display", "none");
+            Mousetrap.reset();
+        },
+        onleaveconnect: function() {
+            d3.select("#connect").style("display", "none");
+            Mousetrap.reset();
+        },
+        onleavesummary: function() {
+            d3.select("#summary").style("display", "none");
+            d3.select("#summary-table tbody").html("");
+            Mousetrap.reset();
+        },
+        onleavewait: function() {
+            d3.select("#wait").style("display", "none");
+        },
         onleavewells: function() {
             d3.select("#wells").style("display", "none");
             d3.select("#wells-table tbody").html("");
+            d3.select("#wells-news").html("");
             Mousetrap.reset();
         },
     }
@@ -115,6 +170,29 @@ function lobby() {
     });
 }
 
+// wait polls the player view until it's this player's turn in a sequential game
+function wait() {
+    d3.select("#wait").style("display", "block");
+
+    updateState();
+
+    function updateState() {
+        d3.json(moveURL())
+            .on("load", function(data) {
+                state = data;
+                if (state.name != "wait") {
+                    return fsm[state.name]();
+                }
+
+                d3.select("#wait-week").text(state.week);
+                d3.select("#wait-turn").text(state.turn);
+                setTimeout(updateState, 1000);
+            })
+            .on("error", console.log)
+            .get();
+    }
+}
+
 function toCurrency(cents, width) {
     s = cents + '';
     s = s.length >= 3 ? s : new Array(3 - s.length + 1).join(0) + s;
@@ -124,14 +202,16 @@ function toCurrency(cents, width) {
 function survey() {
     d3.select("#survey").style("display", "block");
 
+    field = {height: state.height, width: state.width, topology: state.topology};
+
     d3.select("#prob")
         .selectAll("rect")
         .data(state.prob)
         .enter()
         .append("rect")
         .attr("data-site", function (d, i) { return i; })
-        .attr("y", function (d, i) { return Math.floor(i/80) * 18; })
-        .attr("x", function (d, i) { return i%80 * 12 ; })
+        .attr("y", cellY)
+        .attr("x", cellX)
         .style("fill", probColor);
 
     d3.select("#cost")
@@ -140,8 +220,8 @@ function survey() {
         .enter()
         .append("rect")
         .attr("data-site", function (d, i) { return i; })
-        .attr("y", function (d, i) { return Math.floor(i/80) * 18; })
-        .attr("x", function (d, i) { return i%80 * 12 ; })
+        .attr("y", cellY)
+        .attr("x", cellX)
         .style("fill", costColor);
 
     d3.select("#tax")
@@ -150,8 +230,8 @@ function survey() {
         .enter()
         .append("rect")
         .attr("data-site", function (d, i) { return i; })
-        .attr("y", function (d, i) { return Math.floor(i/80) * 18; })
-        .attr("x", function (d, i) { return i%80 * 12 ; })
+        .attr("y", cellY)
+        .attr("x", cellX)
         .style("fill", taxColor);
 
     d3.select("#oil")
@@ -160,8 +240,8 @@ function survey() {
         .enter()
         .append("rect")
         .attr("data-site", function (d, i) { return i; })
-        .attr("y", function (d, i) { return Math.floor(i/80) * 18; })
-        .attr("x", function (d, i) { return i%80 * 12 ; })
+        .attr("y", cellY)
+        .attr("x", cellX)
         .style("fill", function (d) { return d == 0 ? 'black' : oilColor(d); });
 
     d3.select("#fact").text(state.fact);
@@ -171,7 +251,17 @@ function survey() {
 
     d3.selectAll("rect[data-site='0'").attr("class", "cursor");
 
-    var views = ["#prob", "#cost", "#tax", "#oil"];
+    d3.select("#surface")
+        .selectAll("rect")
+        .data(state.surface || [])
+        .enter()
+        .append("rect")
+        .attr("data-site", function (d, i) { return i; })
+        .attr("y", cellY)
+        .attr("x", cellX)
+        .style("fill", surfaceColor);
+
+    var views = ["#prob", "#cost", "#tax", "#oil", "#surface"];
     var cur = 0;
     function view(delta) {
         d3.select(views[cur]).style("display", "none");
@@ -183,9 +273,9 @@ function survey() {
     function cursor(dy, dx) {
         d3.selectAll("rect[data-site='"+site+"']").attr("class", "");
 
-        var y = mod(Math.floor(site/80)+dy, 24);
-        var x = mod(mod(site, 80)+dx, 80);
-        site = y*80 + x;
+        var y = mod(siteY(site)+dy, field.height);
+        var x = mod(siteX(site)+dx, field.width);
+        site = y*field.width + x;
 
         d3.selectAll("rect[data-site='"+site+"']").attr("class", "cursor");
     }
@@ -196,8 +286,10 @@ function survey() {
         d3.json(moveURL())
             .on("load", function(data) {
                 state = data;
-                if (state.name != 'survey') {
+                if (state.name == 'report') {
                     fsm.done();
+                } else if (state.name != 'survey') {
+                    fsm[state.name]();
                 }
             })
             .on("error", console.log)
@@ -244,14 +336,30 @@ function survey() {
         e.preventDefault ? e.preventDefault() : (e.returnValue = false);
         cursor(-3, 0);
     });
+
+    // real-time players may check on their wells between surveys
+    Mousetrap.bind('q', function(e) {
+        e.preventDefault ? e.preventDefault() : (e.returnValue = false);
+
+        d3.json(moveURL())
+            .on("load", function(data) {
+                state = data;
+                if (state.name != 'survey') {
+                    fsm[state.name]();
+                }
+            })
+            .on("error", console.log)
+            .post(JSON.stringify(-1));
+    });
 }
 
 function report() {
     d3.select("#report").style("display", "block");
-    d3.select("#report-site").text("X="+mod(state.site, 80)+"\tY="+Math.floor(state.site/80));
+    d3.select("#report-site").text("X="+siteX(state.site)+"\tY="+siteY(state.site));
     d3.select("#report-prob").text(state.prob + "%");
     d3.select("#report-cost").text("$\t" + state.cost);
     d3.select("#report-tax").text("$\t" + state.tax);
+    d3.select("#report-transport").text(toCurrency(state.transport));
 
     Mousetrap.bind('y', function(e) {
         e.preventDefault ? e.preventDefault() : (e.returnValue = false);
@@ -275,8 +383,122 @@ function report() {
     });
 }
 
-function drill() {
-    advance();
+// yesNo binds the y and n keys to posting the answer and taking the matching transition
+function yesNo() {
+    Mousetrap.bind('y', function(e) {
+        e.preventDefault ? e.preventDefault() : (e.returnValue = false);
+        d3.json(moveURL())
+            .on("load", function(data) {
+                state = data;
+                fsm.yes();
+            })
+            .on("error", console.log)
+            .post(JSON.stringify(1));
+    });
+    Mousetrap.bind('n', function(e) {
+        e.preventDefault ? e.preventDefault() : (e.returnValue = false);
+        d3.json(moveURL())
+            .on("load", function(data) {
+                state = data;
+                fsm.no();
+            })
+            .on("error", console.log)
+            .post(JSON.stringify(0));
+    });
+}
+
+function reenter() {
+    d3.select("#reenter").style("display", "block");
+    d3.select("#reenter-site").text("X="+siteX(state.site)+"\tY="+siteY(state.site));
+    d3.select("#reenter-depth").text(state.depth);
+    d3.select("#reenter-cost").text("$\t" + state.cost);
+    d3.select("#reenter-fee").text("$\t" + state.fee);
+    yesNo();
+}
+
+function summary() {
+    d3.select("#summary").style("display", "block");
+    d3.select("#summary-week").text(state.week);
+    d3.select("#summary-prev-price").text(toCurrency(state.prevPrice));
+    d3.select("#summary-oil-price").text(toCurrency(state.price));
+
+    d3.select("#summary-table tbody")
+        .selectAll("tr")
+        .data(state.players)
+        .enter()
+        .append("tr")
+        .selectAll("td")
+        .data(function(d) { return [d.name, d.income, d.transport, d.taxes, d.drilling, d.discoveries, d.dryHoles, d.pnl]; })
+        .enter()
+        .append("td")
+        .text(function(d) { return d; });
+
+    Mousetrap.bind(['space', 'enter'], function(e) {
+        e.preventDefault ? e.preventDefault() : (e.returnValue = false);
+        d3.json(moveURL())
+            .on("load", function(data) {
+                state = data;
+                fsm[state.name]();
+            })
+            .on("error", console.log)
+            .post(JSON.stringify(-1));
+    });
+}
+
+function workover() {
+    d3.select("#workover").style("display", "block");
+    d3.select("#workover-site").text("X="+siteX(state.site)+"\tY="+siteY(state.site));
+    d3.select("#workover-output").text(state.output);
+    d3.select("#workover-fee").text("$\t" + state.fee);
+    yesNo();
+}
+
+function connect() {
+    d3.select("#connect").style("display", "block");
+    d3.select("#connect-site").text("X="+siteX(state.site)+"\tY="+siteY(state.site));
+    d3.select("#connect-price").text(toCurrency(state.gasPrice));
+    d3.select("#connect-fee").text("$\t" + state.fee);
+    yesNo();
+}
+
+function complete() {
+    d3.select("#complete").style("display", "block");
+    d3.select("#complete-show").text(state.resource.toUpperCase() + " SHOW!");
+    d3.select("#complete-depth").text(state.depth);
+    d3.select("#complete-cost").text(state.cost);
+    d3.select("#complete-pipeline").text(state.pipeline ? "" : "NO PIPELINE NEARBY. GAS WILL BE FLARED.");
+
+    Mousetrap.bind('y', function(e) {
+        e.preventDefault ? e.preventDefault() : (e.returnValue = false);
+        d3.json(moveURL())
+            .on("load", function(data) {
+                state = data;
+                state.name == 'connect' ? fsm.connect() : fsm.yes();
+            })
+            .on("error", console.log)
+            .post(JSON.stringify(1));
+    });
+    Mousetrap.bind('n', function(e) {
+        e.preventDefault ? e.preventDefault() : (e.returnValue = false);
+        d3.json(moveURL())
+            .on("load", function(data) {
+                state = data;
+                // there's nothing deeper to drill for at total depth
+                state.name == 'drill' ? fsm.no() : fsm.done();
+            })
+            .on("error", console.log)
+            .post(JSON.stringify(0));
+    });
+}
+
+function drill(event, from) {
+    // passing up a pay zone returns here with the bit already at the zone
+    if (from == 'complete') {
+        d3.select("#drill-depth").text(state.depth);
+        d3.select("#drill-cost").text(state.cost);
+    } else {
+        advance();
+    }
 
     d3.select("#drill").style("display", "block");
 
@@ -286,6 +508,9 @@ function drill() {
         d3.json(moveURL())
             .on("load", function(data) {
                 state = data;
+                if (state.name == 'complete') {
+                    return fsm.complete()
+                }
                 if (state.name != 'drill') {
                     return fsm.done()
                 }
@@ -322,8 +547,8 @@ function wells() {
     d3.select("#wells").style("display", "block");
 
     function siteData(d) {
-        var x = mod(d.site, 80);
-        var y = Math.floor(d.site/80);
+        var x = siteX(d.site);
+        var y = siteY(d.site);
         var data = [x, y, d.depth, "$", d.cost, "$", d.tax, "$", d.income, "$", d.pnl]
         return data;
     }
@@ -340,18 +565,47 @@ function wells() {
         .text(function(d) { return d; })
         .order();
 
+    d3.select("#wells-news")
+        .selectAll("div")
+        .data(state.incidents || [])
+        .enter()
+        .append("div")
+        .text(function(d) { return d.kind.toUpperCase() + " AT " + siteX(d.site) + "," + siteY(d.site); });
+
     Mousetrap.bind('q', function(e) {
         e.preventDefault ? e.preventDefault() : (e.returnValue = false);
 
         d3.json(moveURL())
             .on("load", function(data) {
                 state = data;
-                fsm.done();
+                // real-time weeks roll on without the lobby
+                state.name == 'survey' ? fsm.survey() : fsm.done();
             })
             .on("error", console.log)
             .post(JSON.stringify(-1));
     });
 }
+function siteY(site) {
+    return Math.floor(site/field.width);
+}
+
+function siteX(site) {
+    return mod(site, field.width);
+}
+
+function cellY(d, site) {
+    return siteY(site) * 18;
+}
+
+// hex grids shift odd rows right by half a cell
+function cellX(d, site) {
+    var x = siteX(site) * 12;
+    if (field.topology.grid == "hex" && siteY(site) % 2 == 1) {
+        x += 6;
+    }
+    return x;
+}
+
 // % operator in javascript is remainder and isn't helpful for wrapping negatives
 function mod(a, n) {
     return a - (n * Math.floor(a/n));
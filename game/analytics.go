@@ -0,0 +1,219 @@
+package game
+
+import (
+	"math"
+	"sort"
+)
+
+// weeks of production counted in a site's expected value
+const horizon = 12
+
+// Distribution summarizes a set of values.
+type Distribution struct {
+	Min    int     `json:"min"`
+	P25    int     `json:"p25"`
+	Median int     `json:"median"`
+	P75    int     `json:"p75"`
+	Max    int     `json:"max"`
+	Mean   float64 `json:"mean"`
+	StdDev float64 `json:"stddev"`
+}
+
+func distribution(values []int) Distribution {
+	if len(values) == 0 {
+		return Distribution{}
+	}
+	sorted := append([]int(nil), values...)
+	sort.Ints(sorted)
+
+	var sum float64
+	for _, v := range sorted {
+		sum += float64(v)
+	}
+	mean := sum / float64(len(sorted))
+	var sq float64
+	for _, v := range sorted {
+		sq += (float64(v) - mean) * (float64(v) - mean)
+	}
+
+	at := func(q float64) int {
+		return sorted[int(q*float64(len(sorted)-1))]
+	}
+	return Distribution{
+		Min:    sorted[0],
+		P25:    at(0.25),
+		Median: at(0.5),
+		P75:    at(0.75),
+		Max:    sorted[len(sorted)-1],
+		Mean:   mean,
+		StdDev: math.Sqrt(sq / float64(len(sorted))),
+	}
+}
+
+// FieldStats describes how generous or stingy a game's field is, for
+// tuning the generators. It reveals where the oil is, so it's for admins
+// and post-mortems, not players.
+type FieldStats struct {
+	Sites int          `json:"sites"`
+	Prob  Distribution `json:"prob"`
+	Cost  Distribution `json:"cost"`
+	Tax   Distribution `json:"tax"`
+
+	// OilSites is how many sites have at least one pay zone.
+	OilSites int `json:"oilSites"`
+	// Reservoirs is how many reservoirs there are, and ReservoirSizes and
+	// ReservoirBarrels the distributions of their sites and oil in place.
+	Reservoirs       int          `json:"reservoirs"`
+	ReservoirSizes   Distribution `json:"reservoirSizes"`
+	ReservoirBarrels Distribution `json:"reservoirBarrels"`
+
+	// Price is the oil price the expected values were worked out at.
+	Price int `json:"price"`
+	// ExpectedValue is each site's profit in dollars at the surveyor's prob:
+	// the chance of striking times the profit from drilling to the
+	// shallowest pay zone and producing alone for the horizon, plus the
+	// chance of a dry hole times the loss from drilling to total depth. Sites
+	// without oil are valued at the field's typical strike.
+	ExpectedValue []int        `json:"expectedValue"`
+	Value         Distribution `json:"value"`
+	Profitable    int          `json:"profitable"`
+
+	// ProbCorrelation is the correlation between the surveyor's prob and
+	// whether a site has oil, from -1 to 1. HitRate is the fraction of
+	// sites with oil in each tenth of prob, from 1-10% up to 91-100%.
+	ProbCorrelation float64     `json:"probCorrelation"`
+	HitRate         [10]float64 `json:"hitRate"`
+}
+
+// FieldStats analyzes the game's field at the current price.
+func (g *game) FieldStats() FieldStats {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	f := g.f
+	if f.labels == nil {
+		f.labelReservoirs()
+	}
+	n := f.height * f.width
+
+	price := g.price
+	if price == 0 {
+		// the game hasn't started; use a typical price
+		price = 100
+	}
+
+	stats := FieldStats{
+		Sites:         n,
+		Prob:          distribution(f.prob),
+		Cost:          distribution(f.cost),
+		Tax:           distribution(f.tax),
+		Reservoirs:    len(f.members),
+		Price:         price,
+		ExpectedValue: make([]int, n),
+	}
+
+	sizes := make([]int, len(f.members))
+	for id, members := range f.members {
+		sizes[id] = len(members)
+	}
+	stats.ReservoirSizes = distribution(sizes)
+	stats.ReservoirBarrels = distribution(f.inPlace)
+
+	// what a strike is worth before the drilling, at each site with oil and
+	// typically
+	revenue := make([]int, n)
+	var typical, depth, strikes int
+	for s := 0; s < n; s++ {
+		if len(f.oil[s]) > 0 {
+			revenue[s] = g.revenue(site(s), price)
+			typical += revenue[s]
+			depth += f.oil[s][0]
+			strikes++
+		}
+	}
+	if strikes > 0 {
+		typical, depth = typical/strikes, depth/strikes
+	}
+
+	var hits, tried [10]int
+	oil := make([]int, n)
+	for s := 0; s < n; s++ {
+		if len(f.oil[s]) > 0 {
+			stats.OilSites++
+			oil[s] = 1
+		}
+		decile := (f.prob[s] - 1) / 10
+		tried[decile]++
+		hits[decile] += oil[s]
+
+		stats.ExpectedValue[s] = g.expectedValue(site(s), revenue[s], typical, depth)
+		if stats.ExpectedValue[s] > 0 {
+			stats.Profitable++
+		}
+	}
+	for i := range hits {
+		if tried[i] > 0 {
+			stats.HitRate[i] = float64(hits[i]) / float64(tried[i])
+		}
+	}
+	stats.Value = distribution(stats.ExpectedValue)
+	stats.ProbCorrelation = correlation(f.prob, oil)
+	return stats
+}
+
+// expectedValue weighs the profit from striking oil at a site against the loss
+// from a dry hole by the surveyor's prob. sites without oil are valued as
+// though they'd struck the typical revenue at the typical depth.
+func (g *game) expectedValue(s site, revenue, typical, depth int) int {
+	f := g.f
+	if len(f.oil[s]) > 0 {
+		depth = f.oil[s][0]
+	} else {
+		revenue = typical
+	}
+	strike := revenue - depth*f.cost[s]
+	dry := -maxOil * f.cost[s]
+	p := float64(f.prob[s]) / 100
+	return int(math.Round(p*float64(strike) + (1-p)*float64(dry)))
+}
+
+// revenue returns what a site's shallowest pay zone earns producing alone for
+// the horizon, after transport and taxes.
+func (g *game) revenue(s site, price int) int {
+	f := g.f
+	depth := f.oil[s][0]
+	id, _ := f.reservoirID(s, depth)
+	value := 0
+	produced := g.produced[id]
+	for age := 1; age <= horizon; age++ {
+		output := f.output(id, produced, age, age)
+		if remaining := f.inPlace[id] - produced; output > remaining {
+			output = remaining
+		}
+		produced += output
+		value += int(float64(output*(price-f.transport(s)))/100) - f.tax[s]
+	}
+	return value
+}
+
+// correlation returns the Pearson correlation of two equally long series, or
+// zero if either is constant.
+func correlation(xs, ys []int) float64 {
+	var sx, sy float64
+	for i := range xs {
+		sx += float64(xs[i])
+		sy += float64(ys[i])
+	}
+	mx, my := sx/float64(len(xs)), sy/float64(len(ys))
+
+	var cov, vx, vy float64
+	for i := range xs {
+		dx, dy := float64(xs[i])-mx, float64(ys[i])-my
+		cov += dx * dy
+		vx += dx * dx
+		vy += dy * dy
+	}
+	if vx == 0 || vy == 0 {
+		return 0
+	}
+	return cov / math.Sqrt(vx*vy)
+}
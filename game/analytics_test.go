@@ -0,0 +1,99 @@
+package game
+
+import (
+	"math"
+	"reflect"
+	"testing"
+)
+
+func TestDistribution(t *testing.T) {
+	d := distribution([]int{9, 1, 5, 3, 7})
+	expect := Distribution{Min: 1, P25: 3, Median: 5, P75: 7, Max: 9, Mean: 5, StdDev: math.Sqrt(8)}
+	if d != expect {
+		t.Errorf("distribution -> %+v; expect %+v", d, expect)
+	}
+	if d := distribution(nil); d != (Distribution{}) {
+		t.Errorf("distribution(nil) -> %+v; expect zero", d)
+	}
+}
+
+func TestCorrelation(t *testing.T) {
+	tests := []struct {
+		xs, ys []int
+		expect float64
+	}{
+		{[]int{1, 2, 3, 4}, []int{0, 0, 1, 1}, 0.894},
+		{[]int{1, 2, 3, 4}, []int{1, 1, 0, 0}, -0.894},
+		{[]int{1, 2, 3, 4}, []int{1, 1, 1, 1}, 0},
+	}
+	for _, test := range tests {
+		if r := correlation(test.xs, test.ys); math.Abs(r-test.expect) > 0.001 {
+			t.Errorf("correlation(%v, %v) -> %.3f; expect %.3f", test.xs, test.ys, r, test.expect)
+		}
+	}
+}
+
+func TestFieldStats(t *testing.T) {
+	f := &field{
+		height: 1,
+		width:  4,
+		prob:   []int{95, 90, 15, 5},
+		cost:   []int{10, 10, 50, 20},
+		oil:    [][]int{{2}, {2}, nil, nil},
+		tax:    []int{10, 10, 10, 10},
+	}
+	g := newTestGame(f)
+	g.price = 100
+
+	stats := g.FieldStats()
+	if stats.OilSites != 2 || stats.Reservoirs != 1 || stats.ReservoirSizes.Max != 2 {
+		t.Errorf("expect 2 oil sites in 1 reservoir of 2 sites; got %d sites in %d reservoirs of up to %d", stats.OilSites, stats.Reservoirs, stats.ReservoirSizes.Max)
+	}
+	// site 3 has no oil, so a 5% strike is valued at the field's typical one
+	typical := g.revenue(0, 100)
+	if expect := int(math.Round(0.05*float64(typical-2*20) + 0.95*float64(-maxOil*20))); stats.ExpectedValue[3] != expect {
+		t.Errorf("dry hole value %d; expect %d", stats.ExpectedValue[3], expect)
+	}
+	if stats.ExpectedValue[0] <= 0 || stats.Profitable != 2 {
+		t.Errorf("expect 2 profitable sites; got %d with values %v", stats.Profitable, stats.ExpectedValue)
+	}
+	if stats.HitRate[9] != 1 || stats.HitRate[8] != 1 || stats.HitRate[1] != 0 {
+		t.Errorf("hit rates %v; expect oil in the 81-100%% tenths only", stats.HitRate)
+	}
+	if stats.ProbCorrelation < 0.9 {
+		t.Errorf("prob correlation %.2f; expect near 1", stats.ProbCorrelation)
+	}
+
+	// a drawn down reservoir is worth less
+	id, _ := f.reservoirID(0, 2)
+	g.produced[id] = f.inPlace[id] / 2
+	if drawn := g.FieldStats().ExpectedValue[0]; drawn >= stats.ExpectedValue[0] {
+		t.Errorf("value after drawdown %d; expect less than %d", drawn, stats.ExpectedValue[0])
+	}
+}
+
+func TestExpectedValue(t *testing.T) {
+	// the same strike at a long and a short prob, and a dry hole at even odds
+	f := &field{
+		height: 1,
+		width:  3,
+		prob:   []int{90, 10, 50},
+		cost:   []int{10, 10, 10},
+		oil:    [][]int{{2}, {2}, nil},
+		tax:    []int{10, 10, 10},
+	}
+	g := newTestGame(f)
+	g.price = 100
+
+	values := g.FieldStats().ExpectedValue
+	revenue := g.revenue(0, 100)
+	strike, dry := float64(revenue-2*10), float64(-maxOil*10)
+	expect := []int{
+		int(math.Round(0.9*strike + 0.1*dry)),
+		int(math.Round(0.1*strike + 0.9*dry)),
+		int(math.Round(0.5*strike + 0.5*dry)),
+	}
+	if !reflect.DeepEqual(values, expect) {
+		t.Errorf("expected values %v; expect %v", values, expect)
+	}
+}
@@ -0,0 +1,94 @@
+package game
+
+import (
+	"log"
+	"time"
+)
+
+// openWeek starts the clock on an Async game's week and tells every player
+// their turn is open.
+func (g *game) openWeek() {
+	g.expired = make(chan struct{})
+	// a resumed week keeps its deadline, and its players have already been
+	// told it's open
+	if g.resumed {
+		return
+	}
+	g.deadline = time.Now().Add(g.weekLength)
+
+	for _, playerID := range g.world.Players() {
+		g.notify(TurnOpened, playerID)
+	}
+}
+
+// awaitDeadline collects finished players until everyone is done or the
+// week's deadline passes. Players still waiting when the reminder comes due
+// are notified; players still playing at the deadline forfeit the rest of
+// their week.
+func (g *game) awaitDeadline(finished <-chan entity) {
+	waiting := make(map[entity]bool)
+	for _, playerID := range g.world.Players() {
+		waiting[playerID] = true
+	}
+
+	var remind <-chan time.Time
+	if g.reminder > 0 && g.reminder < g.weekLength {
+		reminder := time.NewTimer(time.Until(g.deadline.Add(-g.reminder)))
+		defer reminder.Stop()
+		remind = reminder.C
+	}
+	deadline := time.NewTimer(time.Until(g.deadline))
+	defer deadline.Stop()
+
+	for len(waiting) > 0 {
+		g.mu.Unlock()
+		select {
+		case playerID := <-finished:
+			g.mu.Lock()
+			delete(waiting, playerID)
+		case <-remind:
+			g.mu.Lock()
+			for _, playerID := range g.world.Players() {
+				if waiting[playerID] {
+					g.notify(DeadlineNear, playerID)
+				}
+			}
+		case <-deadline.C:
+			g.mu.Lock()
+			log.Printf("week %d deadline passed with %d players unfinished", g.week, len(waiting))
+			close(g.expired)
+			g.unlocked(func() {
+				for len(waiting) > 0 {
+					select {
+					case playerID := <-finished:
+						delete(waiting, playerID)
+					case <-g.done:
+						return
+					}
+				}
+			})
+		case <-g.done:
+			g.mu.Lock()
+			return
+		}
+	}
+}
+
+// notify sends an event to the game's notifier without holding up play. a
+// notification still on its way when the game closes is given up on.
+func (g *game) notify(event Event, playerID entity) {
+	n := Notification{
+		Event:    event,
+		Week:     g.week,
+		Player:   int(playerID),
+		Name:     g.world.Name(playerID),
+		Deadline: g.deadline,
+	}
+	g.running.Add(1)
+	go func() {
+		defer g.running.Done()
+		if err := g.notifier.Notify(g.ctx, n); err != nil {
+			log.Printf("notifying player %d of %s: %s", n.Player, n.Event, err)
+		}
+	}()
+}
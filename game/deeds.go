@@ -0,0 +1,174 @@
+package game
+
+// a deed is an entity with a location, an owner and the state of its drilling.
+// it gains production when it's completed as a well, and a sale week when
+// it's sold.
+
+// owner is the player holding a deed, the week they surveyed it and their
+// profit and loss on it, itemized in its ledger.
+type owner struct {
+	player entity
+	week   int
+	pnl    int
+	ledger []LedgerEntry
+}
+
+// drillState is how deep a deed has been drilled.
+type drillState struct {
+	bit int
+}
+
+// production is a well completed in an oil or gas zone.
+type production struct {
+	zone     int
+	struck   int
+	workover int
+	shutIn   int
+	output   int
+	produced int
+
+	// gas wells were completed in a gas zone. every well's gas is flared
+	// unless it's connected to a pipeline.
+	gas         bool
+	connected   bool
+	gasOutput   int
+	gasProduced int
+}
+
+// reservoirKey identifies an oil or gas reservoir.
+type reservoirKey struct {
+	gas bool
+	id  int
+}
+
+// deedManager holds the deed components, indexed by site, owner and the
+// reservoir a well taps.
+type deedManager struct {
+	locations   store[site]
+	owners      store[*owner]
+	drills      store[*drillState]
+	productions store[*production]
+	sold        store[int]
+
+	bySite      map[site]entity
+	byOwner     map[entity][]entity
+	byReservoir map[reservoirKey][]entity
+}
+
+// AddDeed gives the player the deed to a site they've surveyed.
+func (m *deedManager) AddDeed(e entity, s site, player entity, week int) {
+	if m.bySite == nil {
+		m.bySite = make(map[site]entity)
+		m.byOwner = make(map[entity][]entity)
+	}
+	m.locations.set(e, s)
+	m.owners.set(e, &owner{player: player, week: week})
+	m.drills.set(e, &drillState{})
+	m.bySite[s] = e
+	m.byOwner[player] = append(m.byOwner[player], e)
+}
+
+// DeedAt returns the deed to a site, if it's been surveyed.
+func (m *deedManager) DeedAt(s site) (entity, bool) {
+	e, ok := m.bySite[s]
+	return e, ok
+}
+
+// Deeds returns every deed.
+func (m *deedManager) Deeds() []entity {
+	return m.locations.entities
+}
+
+func (m *deedManager) Location(e entity) site {
+	s, _ := m.locations.get(e)
+	return s
+}
+
+func (m *deedManager) Owner(e entity) *owner {
+	o, _ := m.owners.get(e)
+	return o
+}
+
+// Owned returns the player's deeds in the order they surveyed them.
+func (m *deedManager) Owned(player entity) []entity {
+	return m.byOwner[player]
+}
+
+func (m *deedManager) DrillState(e entity) *drillState {
+	d, _ := m.drills.get(e)
+	return d
+}
+
+// Complete makes the deed a well producing from a reservoir.
+func (m *deedManager) Complete(e entity, p *production, r reservoirKey) {
+	if m.byReservoir == nil {
+		m.byReservoir = make(map[reservoirKey][]entity)
+	}
+	m.productions.set(e, p)
+	m.byReservoir[r] = append(m.byReservoir[r], e)
+}
+
+// Production returns the well's production, or nil if it was never completed.
+func (m *deedManager) Production(e entity) *production {
+	p, _ := m.productions.get(e)
+	return p
+}
+
+// EachWell calls fn for every well that was completed, sold or not.
+func (m *deedManager) EachWell(fn func(e entity, p *production)) {
+	m.productions.each(fn)
+}
+
+// Tapping returns the wells completed in a reservoir.
+func (m *deedManager) Tapping(r reservoirKey) []entity {
+	return m.byReservoir[r]
+}
+
+// Wells returns the wells completed in a gas or oil reservoir, grouped by
+// reservoir.
+func (m *deedManager) Wells(gas bool) map[reservoirKey][]entity {
+	wells := make(map[reservoirKey][]entity)
+	for r, es := range m.byReservoir {
+		if r.gas == gas {
+			wells[r] = es
+		}
+	}
+	return wells
+}
+
+func (m *deedManager) Sell(e entity, week int) {
+	m.sold.set(e, week)
+}
+
+// Sold returns the week the deed was sold, or zero.
+func (m *deedManager) Sold(e entity) int {
+	week, _ := m.sold.get(e)
+	return week
+}
+
+func (m *deedManager) remove(e entity) {
+	if s, ok := m.locations.get(e); ok {
+		delete(m.bySite, s)
+	}
+	if o, ok := m.owners.get(e); ok {
+		m.byOwner[o.player] = without(m.byOwner[o.player], e)
+	}
+	for r, es := range m.byReservoir {
+		m.byReservoir[r] = without(es, e)
+	}
+	m.locations.remove(e)
+	m.owners.remove(e)
+	m.drills.remove(e)
+	m.productions.remove(e)
+	m.sold.remove(e)
+}
+
+// without removes an entity from an index, keeping the others in order.
+func without(index []entity, e entity) []entity {
+	for i, cur := range index {
+		if cur == e {
+			return append(index[:i:i], index[i+1:]...)
+		}
+	}
+	return index
+}
@@ -0,0 +1,57 @@
+package game
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestDeedManager(t *testing.T) {
+	var w world
+	bob, sue := w.NewEntity(), w.NewEntity()
+
+	d1, d2, d3 := w.NewEntity(), w.NewEntity(), w.NewEntity()
+	w.AddDeed(d1, 10, bob, 1)
+	w.AddDeed(d2, 20, sue, 1)
+	w.AddDeed(d3, 30, bob, 2)
+
+	if e, ok := w.DeedAt(20); !ok || e != d2 {
+		t.Errorf("DeedAt(20) -> %d, %t; want %d, true", e, ok, d2)
+	}
+	if _, ok := w.DeedAt(40); ok {
+		t.Errorf("DeedAt(40) -> true; want unsurveyed")
+	}
+	if owned := w.Owned(bob); !reflect.DeepEqual(owned, []entity{d1, d3}) {
+		t.Errorf("Owned(bob) -> %v, want %v", owned, []entity{d1, d3})
+	}
+
+	oil := reservoirKey{false, 0}
+	w.Complete(d1, &production{zone: 2}, oil)
+	w.Complete(d2, &production{zone: 2}, oil)
+	w.Complete(d3, &production{zone: 4, gas: true}, reservoirKey{true, 0})
+	if tapping := w.Tapping(oil); !reflect.DeepEqual(tapping, []entity{d1, d2}) {
+		t.Errorf("Tapping(oil) -> %v, want %v", tapping, []entity{d1, d2})
+	}
+	if wells := w.Wells(true); len(wells) != 1 || len(wells[reservoirKey{true, 0}]) != 1 {
+		t.Errorf("Wells(true) -> %v, want the one gas well", wells)
+	}
+
+	w.Sell(d2, 3)
+	if w.Sold(d2) != 3 || w.Sold(d1) != 0 {
+		t.Errorf("Sold -> %d, %d; want 3, 0", w.Sold(d2), w.Sold(d1))
+	}
+
+	// destroying a deed drops it from every index
+	w.DestroyEntity(d1)
+	if _, ok := w.DeedAt(10); ok {
+		t.Errorf("DeedAt(10) after destroy -> true")
+	}
+	if owned := w.Owned(bob); !reflect.DeepEqual(owned, []entity{d3}) {
+		t.Errorf("Owned(bob) after destroy -> %v, want %v", owned, []entity{d3})
+	}
+	if tapping := w.Tapping(oil); !reflect.DeepEqual(tapping, []entity{d2}) {
+		t.Errorf("Tapping(oil) after destroy -> %v, want %v", tapping, []entity{d2})
+	}
+	if w.Production(d1) != nil || w.Owner(d1) != nil {
+		t.Errorf("destroyed deed still has components")
+	}
+}
@@ -7,6 +7,20 @@ type world struct {
 	nameManager
 	playerManager
 	surveyorManager
+	deedManager
+}
+
+// DestroyEntity removes all of an entity's components.
+func (w *world) DestroyEntity(e entity) {
+	for _, c := range w.components() {
+		c.remove(e)
+	}
+}
+
+// components lists every component store, so destroying an entity can't
+// miss one.
+func (w *world) components() []component {
+	return []component{&w.names, &w.players, &w.surveyors, &w.deedManager}
 }
 
 type entity uint32
@@ -19,87 +33,133 @@ func (m *entities) NewEntity() entity {
 	return entity(atomic.AddUint32(&m.prev, 1))
 }
 
-type playerManager struct {
-	players []entity
+// Claim makes sure a new entity won't reuse e, for rebuilding a world from
+// entities created elsewhere.
+func (m *entities) Claim(e entity) {
+	for {
+		prev := atomic.LoadUint32(&m.prev)
+		if prev >= uint32(e) || atomic.CompareAndSwapUint32(&m.prev, prev, uint32(e)) {
+			return
+		}
+	}
 }
 
-func (m *playerManager) AddPlayer(e entity) {
-	if _, ok := linfind(m.players, e); ok {
+type component interface {
+	remove(e entity)
+}
+
+// store holds one component for a set of entities. It's a sparse set: the
+// values are packed in a slice for iteration, and a map from entity to
+// position makes lookups O(1). Iteration is in the order entities were
+// added, until one is removed and the last takes its place.
+type store[T any] struct {
+	index    map[entity]int
+	entities []entity
+	values   []T
+}
+
+func (s *store[T]) set(e entity, v T) {
+	if i, ok := s.index[e]; ok {
+		s.values[i] = v
 		return
 	}
-	m.players = append(m.players, e)
+	if s.index == nil {
+		s.index = make(map[entity]int)
+	}
+	s.index[e] = len(s.entities)
+	s.entities = append(s.entities, e)
+	s.values = append(s.values, v)
 }
 
-func (m *playerManager) IsPlayer(e entity) bool {
-	_, ok := linfind(m.players, e)
+func (s *store[T]) get(e entity) (T, bool) {
+	if i, ok := s.index[e]; ok {
+		return s.values[i], true
+	}
+	var zero T
+	return zero, false
+}
+
+func (s *store[T]) has(e entity) bool {
+	_, ok := s.index[e]
 	return ok
 }
 
+func (s *store[T]) remove(e entity) {
+	i, ok := s.index[e]
+	if !ok {
+		return
+	}
+	last := len(s.entities) - 1
+	moved := s.entities[last]
+	s.entities[i] = moved
+	s.values[i] = s.values[last]
+	s.index[moved] = i
+	delete(s.index, e)
+
+	var zero T
+	s.values[last] = zero
+	s.entities = s.entities[:last]
+	s.values = s.values[:last]
+}
+
+func (s *store[T]) len() int {
+	return len(s.entities)
+}
+
+// each calls fn for every entity with the component.
+func (s *store[T]) each(fn func(e entity, v T)) {
+	for i, e := range s.entities {
+		fn(e, s.values[i])
+	}
+}
+
+type playerManager struct {
+	players store[struct{}]
+}
+
+func (m *playerManager) AddPlayer(e entity) {
+	m.players.set(e, struct{}{})
+}
+
+func (m *playerManager) IsPlayer(e entity) bool {
+	return m.players.has(e)
+}
+
+// Players returns the players in the order they joined.
 func (m *playerManager) Players() []entity {
-	return m.players
+	return m.players.entities
 }
 
 // surveyorManager tracks which players may survey.
 type surveyorManager struct {
-	index  []entity
+	surveyors store[struct{}]
 }
 
 func (m *surveyorManager) IsSurveyor(e entity) bool {
-	_, ok := linfind(m.index, e)
-	return ok
+	return m.surveyors.has(e)
 }
 
 func (m *surveyorManager) SetSurveyor(e entity) {
-	if _, ok := linfind(m.index, e); ok {
-		return
-	}
-	m.index = append(m.index, e)
+	m.surveyors.set(e, struct{}{})
 }
 
 func (m *surveyorManager) ClearSurveyor(e entity) {
-	if i, ok := linfind(m.index, e); ok {
-		last := len(m.index) - 1
-		m.index[i] = m.index[last]
-		m.index = m.index[:last]
-	}
+	m.surveyors.remove(e)
 }
 
 type nameManager struct {
-	index []entity
-	names []string
+	names store[string]
 }
 
 func (m *nameManager) SetName(e entity, name string) {
-	if i, ok := linfind(m.index, e); ok {
-		m.names[i] = name
-		return
-	}
-	m.index = append(m.index, e)
-	m.names = append(m.names, name)
+	m.names.set(e, name)
 }
 
 func (m *nameManager) Name(e entity) string {
-	if i, ok := linfind(m.index, e); ok {
-		return m.names[i]
-	}
-	return ""
+	name, _ := m.names.get(e)
+	return name
 }
 
 func (m *nameManager) ClearName(e entity) {
-	if i, ok := linfind(m.index, e); ok {
-		last := len(m.names) - 1
-		m.names[i] = m.names[last]
-		m.index[i] = m.index[last]
-		m.names = m.names[:last]
-		m.index = m.index[:last]
-	}
-}
-
-func linfind(index []entity, e entity) (int, bool) {
-	for i, cur := range index {
-		if cur == e {
-			return i, true
-		}
-	}
-	return 0, false
+	m.names.remove(e)
 }
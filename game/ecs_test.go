@@ -69,3 +69,54 @@ func TestPlayerManager(t *testing.T) {
 	assertEqual(true, m.IsPlayer(bar))
 	assertEqual([]entity{foo, bar}, m.Players())
 }
+
+func TestStore(t *testing.T) {
+	var s store[string]
+	s.set(1, "one")
+	s.set(2, "two")
+	s.set(3, "three")
+	s.set(2, "deux")
+
+	if v, ok := s.get(2); !ok || v != "deux" {
+		t.Fatalf("get(2) -> %q, %t; want deux, true", v, ok)
+	}
+	if s.len() != 3 {
+		t.Fatalf("len() -> %d, want 3", s.len())
+	}
+
+	// the last entity fills the hole, and is still found
+	s.remove(1)
+	s.remove(4)
+	if s.has(1) {
+		t.Fatalf("has(1) after remove -> true")
+	}
+	if v, ok := s.get(3); !ok || v != "three" {
+		t.Fatalf("get(3) -> %q, %t; want three, true", v, ok)
+	}
+
+	var got []entity
+	s.each(func(e entity, v string) { got = append(got, e) })
+	if expected := []entity{3, 2}; !reflect.DeepEqual(got, expected) {
+		t.Fatalf("each visited %v, want %v", got, expected)
+	}
+}
+
+func TestDestroyEntity(t *testing.T) {
+	var w world
+	foo := w.NewEntity()
+	bar := w.NewEntity()
+	for _, e := range []entity{foo, bar} {
+		w.AddPlayer(e)
+		w.SetSurveyor(e)
+	}
+	w.SetName(foo, "foo")
+	w.SetName(bar, "bar")
+
+	w.DestroyEntity(foo)
+	if w.IsPlayer(foo) || w.IsSurveyor(foo) || w.Name(foo) != "" {
+		t.Fatalf("destroyed entity still has components")
+	}
+	if !w.IsPlayer(bar) || !w.IsSurveyor(bar) || w.Name(bar) != "bar" {
+		t.Fatalf("destroying foo disturbed bar")
+	}
+}
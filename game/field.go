@@ -3,6 +3,7 @@ package game
 import (
 	"math"
 	"math/rand"
+	"sort"
 )
 
 const (
@@ -14,25 +15,155 @@ const (
 	maxCost = 250
 	minTax  = 100
 	maxTax  = 550
+
+	// barrels of oil originally in place under each site of a reservoir,
+	// plus more for each bit of depth to account for pressure and thickness
+	barrelsPerSite  = 1000
+	barrelsPerDepth = 200
+	// barrels a well can produce per site of its reservoir each week
+	oilRate = 100
 )
 
 type field struct {
-	height, width        int
-	prob, cost, oil, tax []int
+	height, width   int
+	topo            Topology
+	prob, cost, tax []int
+
+	// oil holds the depths of each site's pay zones, shallowest first, and
+	// gas the depths of its gas zones. no site has oil and gas at the same
+	// depth.
+	oil [][]int
+	gas [][]int
+
+	// surface holds what lies on each site, freight what it costs in cents
+	// to ship a barrel from it to market and gathering how many sites it is
+	// from a pipeline. fields built by hand have none of them.
+	surface   []Feature
+	freight   []int
+	gathering []int
+
+	// the oil reservoirs, and the gas reservoirs. fields built by hand are
+	// labeled on first use.
+	reservoirs
+	gasReservoirs reservoirs
+}
+
+// reservoirs are the connected zones of oil or gas in a field.
+type reservoirs struct {
+	// labels holds the reservoir ID of each of a site's zones, in the same
+	// order as its depths, members holds the sites of each reservoir and
+	// inPlace what it originally held, in barrels or mcf.
+	labels  [][]int
+	members [][]site
+	inPlace []int
+	// rate is the most a well can produce per site of its reservoir each
+	// week.
+	rate float64
 }
 
 func newField(height, width int) *field {
-	prob := fill(height, width, 1+rand.Intn(4), minProb, maxProb, 0.05, 0.25, false) // a few well formed peaks
-	oil := probFilter(fill(height, width, 1, minOil, maxOil, 0.1, 0.5, true), prob)  // hardship
+	return generateField(Peaks{}, Topology{}, height, width)
+}
+
+// generateField lays out a new field's layers with the given generator.
+func generateField(gen FieldGenerator, topo Topology, height, width int) *field {
+	var prob, cost []int
+	var oil [][]int
+	if sub, ok := gen.(subsurface); ok {
+		prob, cost, oil = sub.subsurface(topo, height, width)
+	} else {
+		prob = gen.Layer(height, width, LayerSpec{1 + rand.Intn(4), minProb, maxProb, 0.05, 0.25, false, topo}) // a few well formed peaks
+		cost = gen.Layer(height, width, LayerSpec{5 + rand.Intn(5), minCost, maxCost, 0.1, 0.25, true, topo})   // many chaotic peaks
 
-	return &field{
+		// a primary pay zone, and a few sparser strata above and below it
+		depths := func() []int {
+			return gen.Layer(height, width, LayerSpec{1, minOil, maxOil, 0.1, 0.5, true, topo}) // hardship
+		}
+		zones := [][]int{probFilter(depths(), prob)}
+		for i := rand.Intn(3); i > 0; i-- {
+			zones = append(zones, probFilter(probFilter(depths(), prob), prob))
+		}
+		oil = stack(zones...)
+	}
+
+	f := &field{
 		height: height,
 		width:  width,
+		topo:   topo,
 		prob:   prob,
-		cost:   fill(height, width, 5+rand.Intn(5), minCost, maxCost, 0.1, 0.25, true), // many chaotic peaks
+		cost:   cost,
 		oil:    oil,
-		tax:    fill(height, width, 10+rand.Intn(10), minTax, maxTax, 0.1, 0.5, false), // local politics
+		tax:    gen.Layer(height, width, LayerSpec{10 + rand.Intn(10), minTax, maxTax, 0.1, 0.5, false, topo}), // local politics
+	}
+	f.generateSurface()
+	f.routeFreight()
+	f.labelOil()
+	f.generateGas(gen)
+	f.labelGas()
+	return f
+}
+
+// stack combines layers of pay zone depths, where zero is no oil, into
+// each site's sorted list of distinct depths.
+func stack(layers ...[]int) [][]int {
+	if len(layers) == 0 {
+		return nil
+	}
+	oil := make([][]int, len(layers[0]))
+	for _, layer := range layers {
+		for s, depth := range layer {
+			if depth == 0 {
+				continue
+			}
+			oil[s] = addZone(oil[s], depth)
+		}
+	}
+	return oil
+}
+
+// addZone inserts a depth into a site's sorted pay zones, unless it's already there.
+func addZone(zones []int, depth int) []int {
+	i := sort.SearchInts(zones, depth)
+	if i < len(zones) && zones[i] == depth {
+		return zones
+	}
+	zones = append(zones, 0)
+	copy(zones[i+1:], zones[i:])
+	zones[i] = depth
+	return zones
+}
+
+// zone reports whether the site has a pay zone at the given depth.
+func (f *field) zone(s site, depth int) bool {
+	return f.zoneIndex(s, depth) >= 0
+}
+
+// zoneIndex returns the index of the site's pay zone at the given depth, or
+// -1 if it has none there.
+func (f *field) zoneIndex(s site, depth int) int {
+	return zoneIn(f.oil[s], depth)
+}
+
+// zoneIn returns the index of the depth in a site's zones, or -1.
+func zoneIn(zones []int, depth int) int {
+	for i, d := range zones {
+		if d == depth {
+			return i
+		}
+	}
+	return -1
+}
+
+// shallowest returns the depth of each site's first pay zone, or zero for
+// sites without oil.
+func (f *field) shallowest() []int {
+	oil := make([]int, len(f.oil))
+	for s, zones := range f.oil {
+		if len(zones) > 0 {
+			oil[s] = zones[0]
+		}
 	}
+	return oil
 }
 
 func abs(x int) int {
@@ -42,11 +173,11 @@ func abs(x int) int {
 	return x
 }
 
-func closest(height, width, p int, peaks []int) (int, int) {
+func closest(topo Topology, height, width, p int, peaks []int) (int, int) {
 	var minIdx int
 	minDist := height + width
 	for i, q := range peaks {
-		d := abs(p/width-q/width) + abs(p%width-q%width)
+		d := topo.distance(height, width, p, q)
 		if d < minDist {
 			minDist = d
 			minIdx = i
@@ -55,14 +186,14 @@ func closest(height, width, p int, peaks []int) (int, int) {
 	return minIdx, minDist
 }
 
-func fill(height, width, n, min, max int, decay, fuzz float64, inverse bool) []int {
+func fill(topo Topology, height, width, n, min, max int, decay, fuzz float64, inverse bool) []int {
 	var peaks []int
 	for i := 0; i < n; i++ {
 		peaks = append(peaks, rand.Intn(height*width))
 	}
 	values := make([]int, height*width, height*width)
 	for i := 0; i < height*width; i++ {
-		minIdx, minDist := closest(height, width, i, peaks)
+		minIdx, minDist := closest(topo, height, width, i, peaks)
 
 		// ratio of the longest possible distance
 		v := float64(minDist) / float64(height+width)
@@ -106,47 +237,114 @@ func probFilter(vals, p []int) []int {
 	return filtered
 }
 
-func (f *field) neighbors(s site) <-chan site {
-	out := make(chan site)
-	y, x := s/site(f.width), s%site(f.width)
-	go func() {
-		if y-1 >= 0 {
-			out <- site(f.width)*(y-1) + x
-		}
-		if x-1 >= 0 {
-			out <- site(f.width)*y + x - 1
-		}
-		if x+1 < site(f.width) {
-			out <- site(f.width)*y + x + 1
-		}
-		if y+1 < site(f.height) {
-			out <- site(f.width)*(y+1) + x
-		}
-		close(out)
-	}()
-	return out
+func (f *field) neighbors(s site) []site {
+	return f.topo.neighbors(f.height, f.width, s)
+}
+
+// labelReservoirs finds the connected oil and gas reservoirs in every zone
+// and labels each site's zones with the ID of the reservoir they belong to.
+func (f *field) labelReservoirs() {
+	f.labelOil()
+	f.labelGas()
 }
 
-func (f *field) reservoir(s site) []site {
-	var res []site
-	visited := make(map[site]bool)
-	frontier := []site{s}
-	for len(frontier) > 0 {
-		cur := frontier[len(frontier)-1]
-		frontier = frontier[:len(frontier)-1]
-		visited[cur] = true
+func (f *field) labelOil() {
+	f.reservoirs = f.label(f.oil, barrelsPerSite, barrelsPerDepth, oilRate)
+}
 
-		if f.oil[s] == 0 || f.oil[cur] != f.oil[s] {
-			continue
+func (f *field) labelGas() {
+	f.gasReservoirs = f.label(f.gas, mcfPerSite, mcfPerDepth, gasRate)
+}
+
+// label flood fills the reservoirs in a layer of zones, holding perSite plus
+// perDepth for each bit of depth under each of their sites.
+func (f *field) label(layer [][]int, perSite, perDepth int, rate float64) reservoirs {
+	r := reservoirs{labels: make([][]int, len(layer)), rate: rate}
+	for s, zones := range layer {
+		r.labels[s] = make([]int, len(zones))
+		for i := range zones {
+			r.labels[s][i] = -1
 		}
+	}
 
-		for nbr := range f.neighbors(cur) {
-			if _, ok := visited[nbr]; ok {
+	for s, zones := range layer {
+		for i, depth := range zones {
+			if r.labels[s][i] >= 0 {
 				continue
 			}
-			frontier = append(frontier, nbr)
+
+			// flood fill from here, using the members found so far as the queue
+			id := len(r.members)
+			r.labels[s][i] = id
+			res := []site{site(s)}
+			for j := 0; j < len(res); j++ {
+				for _, nbr := range f.neighbors(res[j]) {
+					k := zoneIn(layer[nbr], depth)
+					if k < 0 || r.labels[nbr][k] >= 0 {
+						continue
+					}
+					r.labels[nbr][k] = id
+					res = append(res, nbr)
+				}
+			}
+			r.members = append(r.members, res)
+			r.inPlace = append(r.inPlace, len(res)*(perSite+perDepth*depth))
 		}
-		res = append(res, cur)
 	}
-	return res
+	return r
+}
+
+// output returns a week's production from a well in a reservoir that has
+// already given up produced, for a well struck age weeks ago and last
+// serviced worn weeks ago.
+func (r *reservoirs) output(id, produced, age, worn int) int {
+	size := float64(len(r.members[id]))
+	pressure := 1 - float64(produced)/float64(r.inPlace[id])
+	// ramp up: well capacity approaches the rate per site @ 1.0 pressure
+	capacity := r.rate * (1 - math.Pow(0.5, float64(age)))
+	// and wears down until the next workover
+	capacity *= math.Pow(1-wear, float64(worn))
+	return int(math.Floor(pressure * capacity * size))
+}
+
+// resource returns the zones and reservoirs of oil, or of gas.
+func (f *field) resource(gas bool) ([][]int, *reservoirs) {
+	if f.reservoirs.labels == nil {
+		f.labelReservoirs()
+	}
+	if gas {
+		return f.gas, &f.gasReservoirs
+	}
+	return f.oil, &f.reservoirs
+}
+
+// reservoirID returns the ID of the oil reservoir in the site's pay zone at
+// the given depth, if it has one.
+func (f *field) reservoirID(s site, depth int) (int, bool) {
+	return f.resourceID(false, s, depth)
+}
+
+// resourceID returns the ID of the oil or gas reservoir in the site's zone at
+// the given depth, if it has one.
+func (f *field) resourceID(gas bool, s site, depth int) (int, bool) {
+	layer, r := f.resource(gas)
+	if layer == nil {
+		return 0, false
+	}
+	i := zoneIn(layer[s], depth)
+	if i < 0 {
+		return 0, false
+	}
+	return r.labels[s][i], true
+}
+
+// reservoir returns the sites connected to s through the pay zone at the
+// given depth, or nothing if s has no pay zone there. The sites are shared
+// and must not be modified.
+func (f *field) reservoir(s site, depth int) []site {
+	id, ok := f.reservoirID(s, depth)
+	if !ok {
+		return nil
+	}
+	return f.members[id]
 }
@@ -2,6 +2,7 @@ package game
 
 import (
 	"fmt"
+	"reflect"
 	"sort"
 	"testing"
 )
@@ -29,7 +30,7 @@ func TestNeighbors(t *testing.T) {
 
 	for i, expect := range expect {
 		j := 0
-		for nbr := range f.neighbors(site(i)) {
+		for _, nbr := range f.neighbors(site(i)) {
 			if nbr != expect[j] {
 				t.Errorf("neigbors(%d) -> element at index %d is %d; expect %d", i, j, nbr, expect[j])
 			}
@@ -85,10 +86,11 @@ var reservoirTests = []struct {
 func TestReservoir(t *testing.T) {
 	for i, test := range reservoirTests {
 		f := newField(3, 3)
-		f.oil = test.oil
+		f.oil = stack(test.oil)
+		f.labelReservoirs()
 		for s := 0; s < 9; s++ {
 			var res []int
-			for _, s := range f.reservoir(site(s)) {
+			for _, s := range f.reservoir(site(s), test.oil[s]) {
 				res = append(res, int(s))
 			}
 			sort.Sort(sort.IntSlice(res))
@@ -105,3 +107,116 @@ func TestReservoir(t *testing.T) {
 		}
 	}
 }
+
+func TestReservoirZones(t *testing.T) {
+	f := newField(3, 3)
+
+	// a shallow zone across the top row over a deep zone down the middle column
+	f.oil = stack(
+		[]int{
+			2, 2, 2,
+			0, 0, 0,
+			0, 0, 0},
+		[]int{
+			0, 7, 0,
+			0, 7, 0,
+			0, 7, 0})
+	f.labelReservoirs()
+
+	expect := [][]int{
+		{2}, {2, 7}, {2},
+		nil, {7}, nil,
+		nil, {7}, nil}
+	if !reflect.DeepEqual(f.oil, expect) {
+		t.Fatalf("stack -> %v; expect %v", f.oil, expect)
+	}
+
+	var tests = []struct {
+		s      site
+		depth  int
+		expect []int
+	}{
+		{0, 2, []int{0, 1, 2}},
+		{1, 2, []int{0, 1, 2}},
+		{1, 7, []int{1, 4, 7}},
+		{7, 7, []int{1, 4, 7}},
+		{0, 7, nil},
+		{4, 2, nil},
+	}
+	for _, test := range tests {
+		var res []int
+		for _, s := range f.reservoir(test.s, test.depth) {
+			res = append(res, int(s))
+		}
+		sort.Ints(res)
+		if !reflect.DeepEqual(res, test.expect) {
+			t.Errorf("reservoir(%d, %d) -> %v; expect %v", test.s, test.depth, res, test.expect)
+		}
+	}
+}
+
+func TestLabelReservoirs(t *testing.T) {
+	f := newField(4, 4)
+
+	// a solid block of one zone, a ring of another, and an isolated site
+	f.oil = stack(
+		[]int{
+			3, 3, 0, 0,
+			3, 3, 0, 0,
+			0, 0, 0, 0,
+			0, 0, 0, 5},
+		[]int{
+			6, 6, 6, 0,
+			6, 0, 6, 0,
+			6, 6, 6, 0,
+			0, 0, 0, 0})
+	f.labelReservoirs()
+
+	if len(f.members) != 3 {
+		t.Fatalf("expect 3 reservoirs; got %d: %v", len(f.members), f.members)
+	}
+
+	var tests = []struct {
+		s     site
+		depth int
+		size  int
+		same  site
+	}{
+		{0, 3, 4, 5},
+		{5, 3, 4, 0},
+		{0, 6, 8, 10},
+		{9, 6, 8, 2},
+		{15, 5, 1, 15},
+	}
+	for _, test := range tests {
+		id, ok := f.reservoirID(test.s, test.depth)
+		if !ok {
+			t.Errorf("reservoirID(%d, %d) -> no reservoir", test.s, test.depth)
+			continue
+		}
+		if n := len(f.members[id]); n != test.size {
+			t.Errorf("reservoir at (%d, %d) has %d sites; expect %d", test.s, test.depth, n, test.size)
+		}
+		if other, _ := f.reservoirID(test.same, test.depth); other != id {
+			t.Errorf("reservoirID(%d, %d) -> %d; expect same as site %d (%d)", test.s, test.depth, id, test.same, other)
+		}
+	}
+
+	// the block and the ring overlap at site 0 but are different reservoirs
+	block, _ := f.reservoirID(0, 3)
+	ring, _ := f.reservoirID(0, 6)
+	if block == ring {
+		t.Errorf("expect separate reservoirs for depths 3 and 6 at site 0")
+	}
+	if _, ok := f.reservoirID(5, 6); ok {
+		t.Errorf("expect no reservoir at depth 6 in the middle of the ring")
+	}
+}
+
+func BenchmarkLabelReservoirs(b *testing.B) {
+	f := generateField(Geology{}, Topology{}, 240, 800)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		f.labelReservoirs()
+	}
+}
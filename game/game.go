@@ -1,136 +1,531 @@
 package game
 
 import (
+	"context"
+	"errors"
 	"expvar"
+	"fmt"
+	"io"
 	"log"
-	"math"
 	"math/rand"
 	"sync"
+	"sync/atomic"
 	"time"
 )
 
 var stats = expvar.NewMap("game")
 
+// A Game is played through Join, Status, Move and View, which wait on the
+// game's state machine. They give up with the context's error when it's done.
 type Game interface {
-	Join(string) int
-	Status() View
-	Move(int, int) View
-	View(int) View
+	Join(ctx context.Context, name string) (int, error)
+	Status(ctx context.Context) (View, error)
+	Move(ctx context.Context, playerID, move int) (View, error)
+	View(ctx context.Context, playerID int) (View, error)
+	Map() Map
+	FieldStats() FieldStats
+	Render(io.Writer, RenderOptions) error
+	Journal(week int) []Entry
+	Standings(week int) (View, error)
+	Timeline() (Timeline, error)
+	Frame(week int) (Frame, error)
+	Ledger(playerID int) []LedgerEntry
+	Close() error
+	Done() <-chan struct{}
 }
 
+// ErrNoPlayer is returned for moves and views of players who haven't joined.
+var ErrNoPlayer = errors.New("no such player")
+
+// ErrNotYourTurn is returned for moves made while nothing is waiting on the
+// player's move: between weeks, or once they've finished theirs.
+var ErrNotYourTurn = errors.New("not your turn")
+
 type site int
 
+// Mode selects how players take their turns within a week.
+type Mode int
+
+const (
+	// Simultaneous games let every player survey and drill at once.
+	Simultaneous Mode = iota
+	// Sequential games hand the turn to one player at a time, hot-seat
+	// style. The first mover rotates each week.
+	Sequential
+	// Realtime games advance the week on a wall clock. Players survey and
+	// drill whenever they like, within per-week limits, and production
+	// accrues daily.
+	Realtime
+	// Async games are played by mail. Each week lasts until a deadline,
+	// players move whenever they are online, and a Notifier tells them
+	// when their turn opens and when the deadline draws near.
+	Async
+)
+
+var modes = map[string]Mode{
+	"":             Simultaneous,
+	"simultaneous": Simultaneous,
+	"sequential":   Sequential,
+	"realtime":     Realtime,
+	"async":        Async,
+}
+
+// ParseMode returns the Mode with the given name. The empty name is Simultaneous.
+func ParseMode(name string) (Mode, error) {
+	mode, ok := modes[name]
+	if !ok {
+		return 0, fmt.Errorf("unknown game mode %q", name)
+	}
+	return mode, nil
+}
+
+// Option configures a game created by New.
+type Option func(*game)
+
+// WithMode sets the game's turn mode.
+func WithMode(mode Mode) Option {
+	return func(g *game) {
+		g.mode = mode
+	}
+}
+
+// minWeekLength is the shortest week a game may have: a realtime week needs
+// at least a millisecond for each day.
+const minWeekLength = daysPerWeek * time.Millisecond
+
+// WithWeekLength sets how long a week lasts in a Realtime game, or the
+// deadline for each week in an Async game. Lengths that aren't positive leave
+// the default in place.
+func WithWeekLength(d time.Duration) Option {
+	return func(g *game) {
+		if d <= 0 {
+			return
+		}
+		if d < minWeekLength {
+			d = minWeekLength
+		}
+		g.weekLength = d
+	}
+}
+
+// WithDrillLimit sets how many bits each player may drill per week in a
+// Realtime game.
+func WithDrillLimit(bits int) Option {
+	return func(g *game) {
+		g.drillLimit = bits
+	}
+}
+
+// WithGenerator sets how the game's field is laid out.
+func WithGenerator(gen FieldGenerator) Option {
+	return func(g *game) {
+		g.generator = gen
+	}
+}
+
+// WithTopology sets how the sites of a generated field connect. Maps bring
+// their own topology.
+func WithTopology(topo Topology) Option {
+	return func(g *game) {
+		g.topo = topo
+	}
+}
+
+// WithMap plays the game on a stored map instead of generating a field. The
+// map must be valid.
+func WithMap(m Map) Option {
+	return func(g *game) {
+		g.f = fieldFromMap(m)
+	}
+}
+
+// WithNotifier sets where an Async game sends its turn notifications.
+func WithNotifier(n Notifier) Option {
+	return func(g *game) {
+		g.notifier = n
+	}
+}
+
+// WithReminder sets how long before an Async game's deadline the players
+// who haven't finished their week are reminded.
+func WithReminder(d time.Duration) Option {
+	return func(g *game) {
+		g.reminder = d
+	}
+}
+
 type game struct {
-	world  world
-	join   chan string
-	joinID chan entity
-	move   map[entity]chan site
-	status chan View
-	view   map[entity]chan View
-	f      *field
-	week   int
-	deeds  map[site]*deed
-	price  int
-}
-
-type deed struct {
-	player entity
-	week   int
-	stop   int
-	bit    int
-	output int
-	pnl    int
-}
-
-func New() Game {
+	// mu guards everything below. the state machines hold it while they
+	// work and let go only to wait on players, so every change to the game
+	// and every view of it is made in turn.
+	mu sync.Mutex
+
+	world     world
+	mode      Mode
+	generator FieldGenerator
+	topo      Topology
+	join      chan string
+	joinID    chan entity
+	status    chan View
+	move      map[entity]chan site
+	view      map[entity]chan View
+	wake      map[entity]chan struct{}
+	// players whose moves the state machine is waiting on, each with a
+	// channel closed when it stops
+	awaiting map[entity]chan struct{}
+	turn     entity
+	f        *field
+	week     int
+	price    int
+	gasPrice int
+	// last week's prices
+	prevPrice    int
+	prevGasPrice int
+	// barrels and mcf produced from each oil and gas reservoir
+	produced    map[int]int
+	producedGas map[int]int
+	incidents   []Incident
+
+	// the rules run at the turn of each week, in order
+	systems []namedSystem
+	journal journal
+
+	// real-time clock and limits
+	weekLength time.Duration
+	drillLimit int
+	day        int
+	drilled    map[entity]int
+
+	// play-by-mail deadlines
+	notifier Notifier
+	reminder time.Duration
+	deadline time.Time
+	expired  chan struct{}
+	// the week is carrying on from where a resumed game left off
+	resumed bool
+
+	// closing down, when asked to or once nobody's about. ctx is cancelled
+	// along with done, for work handed off to other goroutines.
+	done        chan struct{}
+	ctx         context.Context
+	cancel      context.CancelFunc
+	closing     sync.Once
+	running     sync.WaitGroup
+	active      atomic.Int64
+	idleTimeout time.Duration
+}
+
+const (
+	// re-entering a well costs as much as drilling this many bits to
+	// cover moving a rig back onto the site
+	remobilizeBits = 3
+	// a workover costs as much as this many weeks of taxes
+	workoverWeeks = 2
+	// wells lose capacity to sand and paraffin each week until a workover
+	wear = 0.05
+)
+
+func New(opts ...Option) Game {
 	rand.Seed(time.Now().UTC().UnixNano())
 
-	g := &game{
-		f:      newField(24, 80),
-		join:   make(chan string),
-		joinID: make(chan entity),
-		move:   make(map[entity]chan site),
-		view:   make(map[entity]chan View),
-		status: make(chan View),
-		deeds:  make(map[site]*deed),
+	g := newGame(opts)
+	if g.f == nil {
+		g.f = generateField(g.generator, g.topo, 24, 80)
 	}
-
-	go g.run()
+	// the journal starts from the field just built. it's appended rather
+	// than recorded, as applying it would only build the same field again
+	g.journal.append(g.week, Created{g.mode, g.f.toMap("")})
+	g.setDefaults()
+	g.start(lobby)
 
 	stats.Add("Created", 1)
 	return g
 }
 
-func (g *game) run() {
-	for state := lobby; state != nil; {
+func newGame(opts []Option) *game {
+	g := &game{
+		generator: Peaks{},
+		join:      make(chan string),
+		joinID:    make(chan entity),
+		move:      make(map[entity]chan site),
+		view:      make(map[entity]chan View),
+		wake:      make(map[entity]chan struct{}),
+		awaiting:  make(map[entity]chan struct{}),
+		status:    make(chan View),
+		produced:  make(map[int]int),
+
+		producedGas: make(map[int]int),
+		systems:     defaultSystems(),
+
+		drillLimit: maxOil,
+		drilled:    make(map[entity]int),
+		notifier:   nopNotifier{},
+		reminder:   time.Hour,
+		done:       make(chan struct{}),
+	}
+	g.ctx, g.cancel = context.WithCancel(context.Background())
+	for _, opt := range opts {
+		opt(g)
+	}
+	return g
+}
+
+// setDefaults fills in the week length and idle timeout the game's mode
+// calls for, where no option set them.
+func (g *game) setDefaults() {
+	if g.weekLength == 0 {
+		g.weekLength = 5 * time.Minute
+		if g.mode == Async {
+			g.weekLength = 24 * time.Hour
+		}
+	}
+	if g.weekLength < minWeekLength {
+		g.weekLength = minWeekLength
+	}
+	if g.idleTimeout == 0 {
+		// play-by-mail players may stay away for a week at a time
+		g.idleTimeout = time.Hour
+		if g.mode == Async {
+			g.idleTimeout = 2 * g.weekLength
+		}
+	}
+	if g.idleTimeout < minIdleTimeout {
+		g.idleTimeout = minIdleTimeout
+	}
+}
+
+// start runs the game's state machine from a state, and watches for the game
+// being abandoned.
+func (g *game) start(state stateFn) {
+	g.touch()
+
+	g.running.Add(2)
+	go g.run(state)
+	go g.watch()
+}
+
+func (g *game) run(state stateFn) {
+	defer g.running.Done()
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	for state != nil {
 		state = state(g)
 	}
 }
 
-func (g *game) Join(name string) int {
+// Join adds a player to the game while it's in the lobby.
+func (g *game) Join(ctx context.Context, name string) (int, error) {
 	stats.Add("Joined", 1)
-	g.join <- name
-	return int(<-g.joinID)
+	g.touch()
+	select {
+	case g.join <- name:
+	case <-ctx.Done():
+		return 0, ctx.Err()
+	case <-g.done:
+		return 0, ErrClosed
+	}
+	// the lobby answers every join it takes
+	return int(<-g.joinID), nil
 }
 
-func (g *game) Move(playerID, move int) View {
+// Move makes a player's move and returns their view after it.
+func (g *game) Move(ctx context.Context, playerID, move int) (View, error) {
 	stats.Add("Moved", 1)
-	g.move[entity(playerID)] <- site(move)
-	return <-g.view[entity(playerID)]
+	g.touch()
+	if g.closed() {
+		return nil, ErrClosed
+	}
+	moves, views, ok := g.channels(entity(playerID))
+	if !ok {
+		return nil, ErrNoPlayer
+	}
+	refused, ok := g.awaited(entity(playerID))
+	if !ok {
+		return nil, ErrNotYourTurn
+	}
+	select {
+	case moves <- site(move):
+	case <-refused:
+		// the player's state moved on before taking the move
+		return nil, ErrNotYourTurn
+	case <-ctx.Done():
+		stats.Add("TimedOut", 1)
+		return nil, ctx.Err()
+	case <-g.done:
+		return nil, ErrClosed
+	}
+	return g.await(ctx, views)
 }
 
 // View returns a JSON serializable object representing the player's current game state.
-func (g *game) View(playerID int) View {
+func (g *game) View(ctx context.Context, playerID int) (View, error) {
 	stats.Add("Viewed", 1)
-	return <-g.view[entity(playerID)]
+	g.touch()
+	if g.closed() {
+		return nil, ErrClosed
+	}
+	_, views, ok := g.channels(entity(playerID))
+	if !ok {
+		return nil, ErrNoPlayer
+	}
+	return g.await(ctx, views)
+}
+
+// channels returns the channels a player's moves and views go through.
+func (g *game) channels(playerID entity) (chan site, chan View, bool) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	move, ok := g.move[playerID]
+	return move, g.view[playerID], ok
 }
 
-// State returns the high-level state of the game: who has joined and has it started.
-func (g *game) Status() View {
-	return <-g.status
+// seat makes the channels a player's moves and views pass through.
+func (g *game) seat(playerID entity) {
+	g.move[playerID] = make(chan site)
+	g.view[playerID] = make(chan View)
+	g.wake[playerID] = make(chan struct{})
+}
+
+// awaited reports whether the state machine is waiting on a player's move,
+// and returns a channel that's closed once it stops.
+func (g *game) awaited(playerID entity) (<-chan struct{}, bool) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	refused, ok := g.awaiting[playerID]
+	return refused, ok
+}
+
+// awaitMoves notes that the state machine is waiting on a player's moves.
+func (g *game) awaitMoves(playerID entity) {
+	if _, ok := g.awaiting[playerID]; !ok {
+		g.awaiting[playerID] = make(chan struct{})
+	}
 }
 
-// game state machine func
+// refuseMoves notes that the state machine has stopped waiting on a
+// player's moves, turning away any still on their way.
+func (g *game) refuseMoves(playerID entity) {
+	if refused, ok := g.awaiting[playerID]; ok {
+		close(refused)
+		delete(g.awaiting, playerID)
+	}
+}
+
+// await waits for a view from the state machine.
+func (g *game) await(ctx context.Context, views <-chan View) (View, error) {
+	select {
+	case v := <-views:
+		return v, nil
+	case <-ctx.Done():
+		stats.Add("TimedOut", 1)
+		return nil, ctx.Err()
+	case <-g.done:
+		return nil, ErrClosed
+	}
+}
+
+// Map exports the game's field.
+func (g *game) Map() Map {
+	return g.f.toMap("")
+}
+
+// Status returns the high-level state of the game: who has joined and has it started.
+func (g *game) Status(ctx context.Context) (View, error) {
+	return g.await(ctx, g.status)
+}
+
+// game state machine func. stateFns run with the game locked.
 type stateFn func(*game) stateFn
 
+// locked renders a view with the game locked.
+func (g *game) locked(view func(*game) View) View {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return view(g)
+}
+
+// unlocked lets go of the game while f waits on players.
+func (g *game) unlocked(f func()) {
+	g.mu.Unlock()
+	defer g.mu.Lock()
+	f()
+}
+
 // lobby is the game state machine function for handling joins before the start of the game.
 func lobby(g *game) stateFn {
-	var start chan site
-
 	stop := make(chan struct{})
 	go func() {
 	Loop:
 		for {
 			select {
-			case g.status <- lobbyView(g):
+			case g.status <- g.locked(lobbyView):
 			case <-stop:
 				break Loop
 			}
 		}
 	}()
 
+	// everyone but the owner idles until the owner starts the week
+	var idle sync.WaitGroup
+	waitIn := func(playerID entity) {
+		idle.Add(1)
+		go func() {
+			defer idle.Done()
+			g.idle(playerID, true, stop)
+		}()
+	}
+	if players := g.world.Players(); len(players) > 1 {
+		for _, playerID := range players[1:] {
+			waitIn(playerID)
+		}
+	}
+	for _, playerID := range g.world.Players() {
+		g.awaitMoves(playerID)
+	}
+
 Loop:
 	for {
 		// player 0 is the owner and her first move is the start signal
-		if len(g.world.Players()) > 0 {
-			start = g.move[g.world.Players()[0]]
+		var start chan site
+		var view chan View
+		if players := g.world.Players(); len(players) > 0 {
+			start, view = g.move[players[0]], g.view[players[0]]
 		}
 
+		v := lobbyView(g)
+		g.mu.Unlock()
 		select {
 		case name := <-g.join:
+			g.mu.Lock()
 			playerID := g.world.NewEntity()
-			g.world.AddPlayer(playerID)
-			g.world.SetName(playerID, name)
-			g.world.SetSurveyor(playerID)
-			g.move[playerID] = make(chan site)
-			g.view[playerID] = make(chan View)
+			g.record(Joined{playerID, name})
+			if g.mode != Sequential {
+				g.world.SetSurveyor(playerID)
+			}
+			g.seat(playerID)
+			g.awaitMoves(playerID)
 			g.joinID <- playerID
+			if len(g.world.Players()) > 1 {
+				waitIn(playerID)
+			}
 			log.Printf("name %s joined as player %d", name, playerID)
+		case view <- v:
+			g.mu.Lock()
 		case <-start:
+			g.mu.Lock()
+			break Loop
+		case <-g.done:
+			g.mu.Lock()
 			break Loop
 		}
 	}
-	close(stop)
+	g.unlocked(func() {
+		close(stop)
+		idle.Wait()
+	})
+	if g.closed() {
+		return nil
+	}
 
 	log.Printf("starting week with %d players", len(g.world.Players()))
 	g.nextWeek()
@@ -145,68 +540,242 @@ func play(g *game) stateFn {
 	Loop:
 		for {
 			select {
-			case g.status <- playView(g):
+			case g.status <- g.locked(playView):
 			case <-stop:
 				break Loop
 			}
 		}
 	}()
 
-	// run a state machine for each player in individual go routines
-	var wg sync.WaitGroup
-	wg.Add(len(g.world.Players()))
+	start := survey
+	if g.mode == Sequential {
+		start = wait
+	}
+	if g.week > 1 {
+		start = summary(start)
+	}
+
+	if g.mode == Async {
+		g.openWeek()
+	}
+
+	// run a state machine for each player in individual go routines.
+	// players who finish early idle until everyone has, and their moves are
+	// turned away.
+	var playing, idle sync.WaitGroup
+	finished := make(chan entity)
+	playing.Add(len(g.world.Players()))
+	idle.Add(len(g.world.Players()))
 	for _, playerID := range g.world.Players() {
-		go func(playerID entity) {
-			defer wg.Done()
-			for state := survey; state != nil; {
+		state := start
+		// players who'd moved before a resumed week was interrupted pick
+		// up at their wells
+		if g.resumed && g.moved(playerID) {
+			state = wells
+		}
+		g.awaitMoves(playerID)
+		go func(playerID entity, state playFn) {
+			defer idle.Done()
+			g.mu.Lock()
+			for state != nil {
 				state = state(g, playerID)
 			}
-		}(playerID)
+			g.refuseMoves(playerID)
+			g.mu.Unlock()
+			if g.mode == Sequential || g.mode == Async {
+				select {
+				case finished <- playerID:
+				case <-g.done:
+				}
+			}
+			playing.Done()
+			g.idle(playerID, false, stop)
+		}(playerID, state)
+	}
+	g.resumed = false
+	if g.mode == Sequential {
+		g.takeTurns(finished)
+	}
+	if g.mode == Realtime {
+		g.tick()
 	}
-	wg.Wait()
-	close(stop)
+	if g.mode == Async {
+		g.awaitDeadline(finished)
+	}
+	g.unlocked(func() {
+		playing.Wait()
+		close(stop)
+		idle.Wait()
+	})
 
+	if g.closed() {
+		return nil
+	}
 	log.Printf("all %d players completed week %d", len(g.world.Players()), g.week)
 
+	// play-by-mail weeks start without waiting in the lobby
+	if g.mode == Async {
+		g.nextWeek()
+		return play
+	}
+
 	return lobby
 }
 
-func (g *game) nextWeek() {
-	g.week++
-	g.price = int(100 * math.Abs(1+rand.NormFloat64()))
+// idle shows a player the lobby until stop is closed, so that a player with
+// nothing to do still gets an answer. moves made in the lobby are ignored;
+// those made after finishing a week aren't taken at all.
+func (g *game) idle(playerID entity, inLobby bool, stop <-chan struct{}) {
+	moves, views, _ := g.channels(playerID)
+	if !inLobby {
+		moves = nil
+	}
+	for {
+		select {
+		case views <- g.locked(lobbyView):
+		case move := <-moves:
+			log.Printf("ignoring move %d from idle player %d", move, playerID)
+		case <-stop:
+			return
+		}
+	}
+}
+
+// deed returns the deed to a surveyed site.
+func (g *game) deed(s site) entity {
+	e, _ := g.world.DeedAt(s)
+	return e
+}
+
+// pnl returns the player's profit and loss over all their deeds.
+func (g *game) pnl(playerID entity) int {
+	pnl := 0
+	for _, e := range g.world.Owned(playerID) {
+		pnl += g.world.Owner(e).pnl
+	}
+	return pnl
+}
+
+// producing reports whether the deed is an unsold well completed in a pay zone.
+func (g *game) producing(e entity) bool {
+	return g.world.Production(e) != nil && g.world.Sold(e) == 0
+}
+
+// reenterable reports whether the player may re-enter the deed to drill
+// deeper: it must be their own unsold, non-producing well from an earlier week
+// with room left to drill.
+func (g *game) reenterable(playerID entity, e entity) bool {
+	o := g.world.Owner(e)
+	return o.player == playerID && g.world.Sold(e) == 0 && o.week < g.week &&
+		g.world.Production(e) == nil && g.world.DrillState(e).bit < maxOil
+}
+
+// remobilizeFee returns the cost of moving a rig back onto a site.
+func (g *game) remobilizeFee(s site) int {
+	return remobilizeBits * g.f.cost[s]
+}
+
+// workoverFee returns the cost of servicing a producing well.
+func (g *game) workoverFee(s site) int {
+	return workoverWeeks * g.f.tax[s]
+}
+
+// earnings returns the well's income, transport and taxes for a full week.
+// flared gas earns nothing.
+func (g *game) earnings(e entity) Earned {
+	s, p := g.world.Location(e), g.world.Production(e)
+	c := Earned{
+		Site:      s,
+		Income:    p.output * g.price / 100,
+		Transport: p.output * g.f.transport(s) / 100,
+		Tax:       g.f.tax[s],
+	}
+	if p.connected {
+		c.GasIncome = p.gasOutput * g.gasPrice / 100
+	}
+	return c
+}
 
-	for s, d := range g.deeds {
-		if d.bit == 0 || d.bit != g.f.oil[s] || d.stop > 0 {
-			continue
+// takeTurns wakes each player in turn order, starting with this week's first
+// mover, and waits for their week to finish before waking the next.
+func (g *game) takeTurns(finished <-chan entity) {
+	for _, playerID := range g.turnOrder() {
+		log.Printf("player %d turn in week %d", playerID, g.week)
+		g.turn = playerID
+		g.world.SetSurveyor(playerID)
+		g.unlocked(func() {
+			select {
+			case g.wake[playerID] <- struct{}{}:
+				select {
+				case <-finished:
+				case <-g.done:
+				}
+			case <-g.done:
+			}
+		})
+		if g.closed() {
+			return
 		}
+	}
+}
+
+// turnOrder returns the players in the order they take their turns this week.
+func (g *game) turnOrder() []entity {
+	players := g.world.Players()
+	if len(players) == 0 {
+		return nil
+	}
+	first := (g.week - 1) % len(players)
+	order := append([]entity(nil), players[first:]...)
+	return append(order, players[:first]...)
+}
+
+func (g *game) nextWeek() {
+	g.record(WeekBegan{g.week + 1})
+	for _, s := range g.systems {
+		s.run(g)
+	}
+}
 
-		// production considers reservoir pressure over time
-		res := g.f.reservoir(s)
-		tot := float64(len(res))
-		for _, s := range res {
-			d := g.deeds[s]
-			if d == nil || d.bit == 0 || d.bit != g.f.oil[s] {
+// produce works out how much the oil or gas wells draw from their
+// reservoirs for a week. production considers reservoir pressure, which falls
+// as what's in place is drawn down by every well tapping the reservoir.
+// whoever pumps fastest gets the most of it.
+func (g *game) produce(gas bool) map[entity]int {
+	_, r := g.f.resource(gas)
+	produced := g.produced
+	if gas {
+		produced = g.producedGas
+	}
+
+	outputs := make(map[entity]int)
+	for key, wells := range g.world.Wells(gas) {
+		id := key.id
+		demand := 0
+		for _, e := range wells {
+			p := g.world.Production(e)
+			if !g.producing(e) || p.shutIn == g.week {
 				continue
 			}
-			until := d.stop
-			if until == 0 {
-				until = g.week
+			serviced := p.struck
+			if p.workover > serviced {
+				serviced = p.workover
 			}
-			// pressure diminishes 1/3 per pump site week. with a large enough
-			// reservoir this is subtle but for a small reservoir it's devastating
-			tot -= 1.0 - math.Pow(0.666, float64(until-d.week))
+			outputs[e] = r.output(id, produced[id], g.week-p.struck, g.week-serviced)
+			demand += outputs[e]
 		}
-		pressure := tot / float64(len(res))
-		// ramp up: well capacity approaches 100 barrels per site @ 1.0 pressure
-		capacity := 100 * (1 - math.Pow(0.5, float64(g.week-d.week)))
-		output := int(math.Floor(pressure * capacity * float64(len(res))))
-		log.Printf("reservoir %d size %d capacity %f pressure %f output %d", res, len(res), capacity, pressure, output)
-
-		d.output = output
-		d.pnl += int(float64(d.output*g.price)/100) - g.f.tax[s]
-	}
 
-	for _, player := range g.world.Players() {
-		g.world.SetSurveyor(player)
+		// share out the last of a reservoir when its wells want more than is left
+		remaining := r.inPlace[id] - produced[id]
+		for _, e := range wells {
+			if !g.producing(e) {
+				continue
+			}
+			if demand > remaining {
+				outputs[e] = outputs[e] * remaining / demand
+			}
+			log.Printf("%s reservoir %d size %d produced %d of %d; site %d output %d", resourceName(gas), id, len(r.members[id]), produced[id], r.inPlace[id], g.world.Location(e), outputs[e])
+		}
 	}
+	return outputs
 }
@@ -1,6 +1,17 @@
 package game
 
-import "testing"
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"sync"
+	"testing"
+	"time"
+)
 
 type testGame struct {
 	f     *field
@@ -27,10 +38,7 @@ var tg = testGame{
 			10, 10, 10,
 			10, 10, 10,
 			10, 10, 10},
-		oil: []int{
-			0, 0, 0,
-			0, 0, 0,
-			0, 0, 0},
+		oil: make([][]int, 9),
 		tax: []int{
 			100, 100, 100,
 			100, 100, 100,
@@ -60,12 +68,11 @@ var tg = testGame{
 }
 
 func TestGame(t *testing.T) {
-	g := New().(*game)
-	g.f = tg.f
+	g := New(WithMap(tg.f.toMap(""))).(*game)
 
 	var players []int
 	for _, name := range tg.joins {
-		playerID := g.Join(name)
+		playerID := join(t, g, name)
 		players = append(players, playerID)
 
 		actual := g.world.Name(entity(playerID))
@@ -75,7 +82,7 @@ func TestGame(t *testing.T) {
 	}
 
 	// start the game
-	g.Move(players[0], done)
+	makeMove(t, g, players[0], done)
 
 	for i, tw := range tg.weeks {
 		week := i + 1
@@ -84,41 +91,45 @@ func TestGame(t *testing.T) {
 			t.Errorf("expect week %d; got %d", week, g.week)
 			return
 		}
+		for _, p := range players {
+			dismissSummary(t, g, p)
+		}
 
 		// surveys
 		for i, s := range tw.surveys {
 			p := players[i]
-			g.Move(p, s)
-			deed := g.deeds[site(s)]
-			if int(deed.player) != players[i] {
-				t.Errorf("surveying (week %d player %d site %d): expect owner %d; got %d", g.week, p, s, p, deed.player)
+			makeMove(t, g, p, s)
+			o := g.world.Owner(g.deed(site(s)))
+			if int(o.player) != players[i] {
+				t.Errorf("surveying (week %d player %d site %d): expect owner %d; got %d", g.week, p, s, p, o.player)
 			}
 		}
 
 		// surveyor's reports
 		for i, yesNo := range tw.reports {
-			g.Move(players[i], yesNo)
+			makeMove(t, g, players[i], yesNo)
 		}
 
 		// drilling
 		for i, n := range tw.drills {
 			p := players[i]
 			for j := 0; j < n; j++ {
-				g.Move(p, 0)
+				makeMove(t, g, p, 0)
 			}
 			s := site(tw.surveys[i])
-			if g.deeds[s].bit != n {
-				t.Errorf("drilling (week %d player %d site %d): expect bit %d; got %d", g.week, p, s, n, g.deeds[s].bit)
+			e := g.deed(s)
+			if bit := g.world.DrillState(e).bit; bit != n {
+				t.Errorf("drilling (week %d player %d site %d): expect bit %d; got %d", g.week, p, s, n, bit)
 			}
-			if tw.drills[i] > 0 && g.deeds[s].week != g.week {
-				t.Errorf("drilling (week %d player %d site %d): expect start %d; got %d", g.week, p, s, g.week, g.deeds[s].week)
+			if week := g.world.Owner(e).week; tw.drills[i] > 0 && week != g.week {
+				t.Errorf("drilling (week %d player %d site %d): expect start %d; got %d", g.week, p, s, g.week, week)
 			}
 		}
 
 		// stop drilling where we were
 		for i, yesNo := range tw.reports {
 			if yesNo == yes {
-				g.Move(players[i], done)
+				makeMove(t, g, players[i], done)
 			}
 		}
 
@@ -126,17 +137,757 @@ func TestGame(t *testing.T) {
 		for i, sells := range tw.sells {
 			p := players[i]
 			for _, s := range sells {
-				g.Move(p, s)
+				makeMove(t, g, p, s)
 
 				s := site(s)
-				if g.deeds[s].stop != g.week {
-					t.Errorf("selling (week %d player %d site %d): expect stop %d; got %d", g.week, p, s, g.week, g.deeds[s].stop)
+				if stop := g.world.Sold(g.deed(s)); stop != g.week {
+					t.Errorf("selling (week %d player %d site %d): expect stop %d; got %d", g.week, p, s, g.week, stop)
+				}
+			}
+			makeMove(t, g, p, done)
+		}
+
+		// begin next week
+		makeMove(t, g, players[0], 0)
+	}
+}
+
+// dismissSummary moves the player past the summary that opens every week
+// after the first.
+func dismissSummary(t *testing.T, g *game, playerID int) {
+	if g.week == 1 {
+		return
+	}
+	if v := view(t, g, playerID); viewName(t, v) != "summary" {
+		t.Fatalf("week %d player %d: expect summary; got %s", g.week, playerID, viewName(t, v))
+	}
+	makeMove(t, g, playerID, done)
+}
+
+// join, makeMove, view and status play a test game, failing the test when the
+// game doesn't answer within a second.
+func join(t *testing.T, g *game, name string) int {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	playerID, err := g.Join(ctx, name)
+	if err != nil {
+		t.Fatalf("Join(%q) -> %s", name, err)
+	}
+	return playerID
+}
+
+// makeMove makes a move, waiting out the turn of the week if the next state
+// isn't yet ready for it.
+func makeMove(t *testing.T, g *game, playerID, m int) View {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	v, err := g.Move(ctx, playerID, m)
+	for err == ErrNotYourTurn && ctx.Err() == nil {
+		time.Sleep(time.Millisecond)
+		v, err = g.Move(ctx, playerID, m)
+	}
+	if err != nil {
+		t.Fatalf("week %d: Move(%d, %d) -> %s", g.week, playerID, m, err)
+	}
+	return v
+}
+
+func view(t *testing.T, g *game, playerID int) View {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	v, err := g.View(ctx, playerID)
+	if err != nil {
+		t.Fatalf("week %d: View(%d) -> %s", g.week, playerID, err)
+	}
+	return v
+}
+
+func status(t *testing.T, g *game) View {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	v, err := g.Status(ctx)
+	if err != nil {
+		t.Fatalf("Status() -> %s", err)
+	}
+	return v
+}
+
+// viewName returns the name of the client state represented by a view.
+func viewName(t *testing.T, v View) string {
+	js, err := json.Marshal(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var named struct {
+		Name string `json:"name"`
+	}
+	if err := json.Unmarshal(js, &named); err != nil {
+		t.Fatal(err)
+	}
+	return named.Name
+}
+
+// awaitTurn polls the player's view until they are no longer waiting.
+func awaitTurn(t *testing.T, g *game, playerID int) {
+	deadline := time.Now().Add(time.Second)
+	for time.Now().Before(deadline) {
+		if viewName(t, view(t, g, playerID)) != "wait" {
+			return
+		}
+	}
+	t.Fatalf("player %d never got a turn", playerID)
+}
+
+func TestGameAnswers(t *testing.T) {
+	g := New(WithMap(testMap())).(*game)
+	bob := join(t, g, "bob")
+	sue := join(t, g, "sue")
+
+	// everyone sees the lobby, and moves that don't start the week are ignored
+	for _, p := range []int{bob, sue} {
+		if v := view(t, g, p); viewName(t, v) != "lobby" {
+			t.Errorf("player %d in the lobby viewed %s; expect lobby", p, viewName(t, v))
+		}
+	}
+	if v := makeMove(t, g, sue, done); viewName(t, v) != "lobby" {
+		t.Errorf("sue's move in the lobby -> %s; expect lobby", viewName(t, v))
+	}
+
+	ctx := context.Background()
+	if _, err := g.View(ctx, 99); err != ErrNoPlayer {
+		t.Errorf("View(99) -> %v; expect ErrNoPlayer", err)
+	}
+	if _, err := g.Move(ctx, 99, done); err != ErrNoPlayer {
+		t.Errorf("Move(99) -> %v; expect ErrNoPlayer", err)
+	}
+
+	makeMove(t, g, bob, done)
+
+	// a move that makes no sense in the state is ignored
+	if v := makeMove(t, g, bob, done); viewName(t, v) != "survey" {
+		t.Errorf("bob's off-field survey -> %s; expect survey", viewName(t, v))
+	}
+
+	// players who have finished the week still see the lobby, but their
+	// moves are turned away until the next state
+	makeMove(t, g, bob, 0)
+	makeMove(t, g, bob, no)
+	makeMove(t, g, bob, done)
+	if v := view(t, g, bob); viewName(t, v) != "lobby" {
+		t.Errorf("bob done for the week viewed %s; expect lobby", viewName(t, v))
+	}
+	if _, err := g.Move(ctx, bob, 0); err != ErrNotYourTurn {
+		t.Errorf("Move after the week -> %v; expect ErrNotYourTurn", err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if _, err := g.Join(ctx, "tom"); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Join during the week -> %v; expect deadline exceeded", err)
+	}
+
+	makeMove(t, g, sue, 4)
+	makeMove(t, g, sue, no)
+	makeMove(t, g, sue, done)
+	if v := view(t, g, bob); viewName(t, v) != "lobby" {
+		t.Errorf("bob after the week viewed %s; expect lobby", viewName(t, v))
+	}
+}
+
+// a player finishing their week from several tabs at once gets one answer
+// for the move that finished it, and every other move is turned away rather
+// than left waiting.
+func TestRacingMoves(t *testing.T) {
+	g := New(WithMap(testMap())).(*game)
+	defer g.Close()
+	bob := join(t, g, "bob")
+	join(t, g, "sue")
+	makeMove(t, g, bob, done)
+	makeMove(t, g, bob, 0)
+	makeMove(t, g, bob, no)
+
+	start := make(chan struct{})
+	errs := make(chan error, 64)
+	for i := 0; i < cap(errs); i++ {
+		go func() {
+			ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+			defer cancel()
+			<-start
+			_, err := g.Move(ctx, bob, done)
+			errs <- err
+		}()
+	}
+	close(start)
+	answered := 0
+	for i := 0; i < cap(errs); i++ {
+		switch err := <-errs; err {
+		case nil:
+			answered++
+		case ErrNotYourTurn:
+		default:
+			t.Errorf("racing Move -> %v; expect an answer or ErrNotYourTurn", err)
+		}
+	}
+	if answered != 1 {
+		t.Errorf("%d racing moves answered; expect 1", answered)
+	}
+
+	// a move that got past the check just as the player's state moved on is
+	// turned away rather than left waiting for its deadline
+	g.mu.Lock()
+	g.awaitMoves(entity(bob))
+	g.mu.Unlock()
+	moved := make(chan error)
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+		_, err := g.Move(ctx, bob, done)
+		moved <- err
+	}()
+	time.Sleep(10 * time.Millisecond)
+	g.mu.Lock()
+	g.refuseMoves(entity(bob))
+	g.mu.Unlock()
+	if err := <-moved; err != ErrNotYourTurn {
+		t.Errorf("refused Move -> %v; expect ErrNotYourTurn", err)
+	}
+}
+
+// run with -race: players surveying the same site at once, while onlookers
+// watch, must leave exactly one deed to it.
+func TestSimultaneousSurveys(t *testing.T) {
+	g := New(WithMap(testMap())).(*game)
+	var players []int
+	for i := 0; i < 8; i++ {
+		players = append(players, join(t, g, fmt.Sprintf("player%d", i)))
+	}
+	makeMove(t, g, players[0], done)
+
+	stop := make(chan struct{})
+	var watching sync.WaitGroup
+	watching.Add(1)
+	go func() {
+		defer watching.Done()
+		ctx := context.Background()
+		for {
+			select {
+			case <-stop:
+				return
+			default:
+			}
+			g.Status(ctx)
+			g.View(ctx, players[1])
+			g.Ledger(players[1])
+			g.FieldStats()
+		}
+	}()
+
+	views := make(chan View, len(players))
+	var surveying sync.WaitGroup
+	for _, p := range players {
+		surveying.Add(1)
+		go func(p int) {
+			defer surveying.Done()
+			ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+			defer cancel()
+			v, err := g.Move(ctx, p, 2)
+			if err != nil {
+				t.Errorf("player %d surveying -> %s", p, err)
+			}
+			views <- v
+		}(p)
+	}
+	surveying.Wait()
+	close(stop)
+	watching.Wait()
+	close(views)
+
+	reports := 0
+	for v := range views {
+		switch name := viewName(t, v); name {
+		case "report":
+			reports++
+		case "survey":
+		default:
+			t.Errorf("surveying a site: expect report or survey; got %s", name)
+		}
+	}
+	if reports != 1 {
+		t.Errorf("%d players got the report on site 2; expect 1", reports)
+	}
+	surveys := 0
+	for _, e := range g.Journal(1) {
+		if e.Kind == "surveyed" {
+			surveys++
+		}
+	}
+	if surveys != 1 {
+		t.Errorf("site 2 surveyed %d times; expect 1", surveys)
+	}
+}
+
+func TestSequentialGame(t *testing.T) {
+	g := New(WithMap(tg.f.toMap("")), WithMode(Sequential)).(*game)
+
+	var players []int
+	for _, name := range tg.joins {
+		players = append(players, join(t, g, name))
+	}
+
+	// start the game
+	makeMove(t, g, players[0], done)
+
+	for week := 1; week <= 3; week++ {
+		if g.week != week {
+			t.Fatalf("expect week %d; got %d", week, g.week)
+		}
+		for _, p := range players {
+			dismissSummary(t, g, p)
+		}
+
+		// the first mover rotates each week
+		first := (week - 1) % len(players)
+		for i := range players {
+			p := players[(first+i)%len(players)]
+
+			s := 3*(week-1) + (first+i)%len(players)
+
+			awaitTurn(t, g, p)
+			if int(g.turn) != p {
+				t.Errorf("week %d turn %d: expect player %d; got %d", week, i, p, g.turn)
+			}
+
+			// players later in the order can't move until their turn
+			for j := i + 1; j < len(players); j++ {
+				other := players[(first+j)%len(players)]
+				if v := makeMove(t, g, other, s); viewName(t, v) != "wait" {
+					t.Errorf("week %d player %d out of turn: expect wait; got %s", week, other, viewName(t, v))
+				}
+				if _, ok := g.world.DeedAt(site(s)); ok {
+					t.Errorf("week %d player %d out of turn: surveyed site %d", week, other, s)
 				}
 			}
-			g.Move(p, done)
+
+			if v := makeMove(t, g, p, s); viewName(t, v) != "report" {
+				t.Errorf("week %d player %d survey: expect report; got %s", week, p, viewName(t, v))
+			}
+			makeMove(t, g, p, no)
+			makeMove(t, g, p, done)
 		}
 
 		// begin next week
-		g.Move(players[0], 0)
+		makeMove(t, g, players[0], 0)
+	}
+}
+
+func TestRealtimeGame(t *testing.T) {
+	f := &field{
+		height: 1,
+		width:  3,
+		prob:   []int{50, 50, 50},
+		cost:   []int{10, 10, 10},
+		oil:    [][]int{{2}, nil, nil},
+		tax:    []int{100, 100, 100},
+	}
+
+	g := New(WithMap(f.toMap("")), WithMode(Realtime), WithWeekLength(70*time.Millisecond), WithDrillLimit(1)).(*game)
+
+	p := join(t, g, "bob")
+	makeMove(t, g, p, done)
+
+	makeMove(t, g, p, 0)
+	makeMove(t, g, p, yes)
+
+	// the first bit hits the limit; the second is refused until next week
+	makeMove(t, g, p, 0)
+	makeMove(t, g, p, 0)
+	if bit := g.world.DrillState(g.deed(0)).bit; bit != 1 {
+		t.Fatalf("drilling past limit: expect bit 1; got %d", bit)
+	}
+
+	awaitWeek(t, g, 2)
+	if v := makeMove(t, g, p, 0); viewName(t, v) != "complete" {
+		t.Fatalf("drilling in week 2: expect complete; got %s", viewName(t, v))
+	}
+	if bit := g.world.DrillState(g.deed(0)).bit; bit != 2 {
+		t.Fatalf("drilling in week 2: expect bit 2; got %d", bit)
+	}
+	makeMove(t, g, p, yes)
+
+	// wells return to the survey rather than the lobby
+	if v := makeMove(t, g, p, done); viewName(t, v) != "survey" {
+		t.Fatalf("done selling: expect survey; got %s", viewName(t, v))
+	}
+
+	// production accrues within the week once the well has output
+	awaitWeek(t, g, 3)
+	pnl := g.locked(func(g *game) View { return g.pnl(entity(p)) }).(int)
+	awaitWeek(t, g, 4)
+	if g.locked(func(g *game) View { return g.pnl(entity(p)) }) == pnl {
+		t.Errorf("expect pnl to change from %d during week 3", pnl)
+	}
+}
+
+// awaitWeek polls the game status until the week has started.
+func awaitWeek(t *testing.T, g *game, week int) {
+	deadline := time.Now().Add(time.Second)
+	for time.Now().Before(deadline) {
+		if g.locked(func(g *game) View { return g.week }).(int) >= week {
+			return
+		}
+		time.Sleep(time.Millisecond)
+	}
+	t.Fatalf("week %d never started", week)
+}
+
+type chanNotifier chan Notification
+
+func (c chanNotifier) Notify(ctx context.Context, n Notification) error {
+	select {
+	case c <- n:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func TestAsyncGame(t *testing.T) {
+	notes := make(chanNotifier, 16)
+	g := New(WithMap(tg.f.toMap("")), WithMode(Async), WithWeekLength(100*time.Millisecond), WithReminder(50*time.Millisecond), WithNotifier(notes)).(*game)
+
+	bob := join(t, g, "bob")
+	peter := join(t, g, "peter")
+	makeMove(t, g, bob, done)
+
+	expectNotes := func(event Event, week int, players ...int) {
+		expect := make(map[int]bool)
+		for _, p := range players {
+			expect[p] = true
+		}
+		for range players {
+			select {
+			case n := <-notes:
+				if n.Event != event || n.Week != week || !expect[n.Player] {
+					t.Errorf("unexpected notification %+v; expect %s in week %d for %v", n, event, week, players)
+				}
+				delete(expect, n.Player)
+			case <-time.After(time.Second):
+				t.Fatalf("missing %s notification in week %d for %v", event, week, players)
+			}
+		}
+	}
+
+	expectNotes(TurnOpened, 1, bob, peter)
+
+	// bob plays a week; peter never shows up
+	makeMove(t, g, bob, 0)
+	makeMove(t, g, bob, no)
+	makeMove(t, g, bob, done)
+
+	expectNotes(DeadlineNear, 1, peter)
+
+	// the deadline starts week 2 without anyone in the lobby
+	expectNotes(TurnOpened, 2, bob, peter)
+	if g.week != 2 {
+		t.Errorf("expect week 2 after deadline; got %d", g.week)
+	}
+
+	// peter's unfinished week doesn't hold up the next one
+	dismissSummary(t, g, peter)
+	if v := makeMove(t, g, peter, 1); viewName(t, v) != "report" {
+		t.Errorf("peter surveying in week 2: expect report; got %s", viewName(t, v))
+	}
+}
+
+func TestReenterWell(t *testing.T) {
+	f := &field{
+		height: 1,
+		width:  3,
+		prob:   []int{50, 50, 50},
+		cost:   []int{10, 10, 10},
+		oil:    [][]int{{3}, nil, nil},
+		tax:    []int{100, 100, 100},
+	}
+
+	g := New(WithMap(f.toMap(""))).(*game)
+
+	p := join(t, g, "bob")
+	makeMove(t, g, p, done)
+
+	// week 1: stop one bit short of the pay zone
+	makeMove(t, g, p, 0)
+	makeMove(t, g, p, yes)
+	makeMove(t, g, p, 0)
+	makeMove(t, g, p, 0)
+	makeMove(t, g, p, done)
+	makeMove(t, g, p, done)
+	makeMove(t, g, p, 0)
+
+	// week 2: re-enter and deepen the well
+	dismissSummary(t, g, p)
+	account := g.world.Owner(g.deed(0))
+	pnl := account.pnl
+	if v := makeMove(t, g, p, 0); viewName(t, v) != "reenter" {
+		t.Fatalf("surveying own dry hole: expect reenter; got %s", viewName(t, v))
+	}
+	makeMove(t, g, p, yes)
+	if expect := pnl - remobilizeBits*10; account.pnl != expect {
+		t.Errorf("re-entering: expect pnl %d; got %d", expect, account.pnl)
+	}
+	if v := makeMove(t, g, p, 0); viewName(t, v) != "complete" {
+		t.Fatalf("deepening to the pay zone: expect complete; got %s", viewName(t, v))
+	}
+	if v := makeMove(t, g, p, yes); viewName(t, v) != "wells" {
+		t.Fatalf("completing the pay zone: expect wells; got %s", viewName(t, v))
+	}
+	well := g.world.Production(g.deed(0))
+	if bit := g.world.DrillState(g.deed(0)).bit; bit != 3 || well.struck != 2 {
+		t.Errorf("deepening: expect bit 3 struck in week 2; got bit %d struck in week %d", bit, well.struck)
+	}
+	makeMove(t, g, p, done)
+	makeMove(t, g, p, 0)
+
+	// week 3: the producing well can be worked over but not re-entered
+	dismissSummary(t, g, p)
+	pnl = account.pnl
+	if v := makeMove(t, g, p, 0); viewName(t, v) != "workover" {
+		t.Fatalf("surveying own producing well: expect workover; got %s", viewName(t, v))
+	}
+	if v := makeMove(t, g, p, yes); viewName(t, v) != "wells" {
+		t.Fatalf("working over: expect wells; got %s", viewName(t, v))
+	}
+	if expect := pnl - workoverWeeks*100; account.pnl != expect {
+		t.Errorf("working over: expect pnl %d; got %d", expect, account.pnl)
+	}
+	if well.workover != 3 {
+		t.Errorf("working over: expect workover in week 3; got %d", well.workover)
+	}
+}
+
+func TestPayZones(t *testing.T) {
+	f := &field{
+		height: 1,
+		width:  3,
+		prob:   []int{50, 50, 50},
+		cost:   []int{10, 10, 10},
+		oil:    [][]int{{2, 5}, {1}, nil},
+		tax:    []int{100, 100, 100},
+	}
+
+	g := New(WithMap(f.toMap(""))).(*game)
+
+	p := join(t, g, "bob")
+	makeMove(t, g, p, done)
+
+	makeMove(t, g, p, 0)
+	makeMove(t, g, p, yes)
+	makeMove(t, g, p, 0)
+	if v := makeMove(t, g, p, 0); viewName(t, v) != "complete" {
+		t.Fatalf("striking the first zone: expect complete; got %s", viewName(t, v))
+	}
+
+	// pass up the shallow zone for the deeper one
+	if v := makeMove(t, g, p, no); viewName(t, v) != "drill" {
+		t.Fatalf("passing the first zone: expect drill; got %s", viewName(t, v))
+	}
+	for bit := 3; bit < 5; bit++ {
+		if v := makeMove(t, g, p, 0); viewName(t, v) != "drill" {
+			t.Fatalf("drilling bit %d: expect drill; got %s", bit, viewName(t, v))
+		}
+	}
+	if v := makeMove(t, g, p, 0); viewName(t, v) != "complete" {
+		t.Fatalf("striking the second zone: expect complete; got %s", viewName(t, v))
+	}
+	makeMove(t, g, p, yes)
+
+	e := g.deed(0)
+	bit, well := g.world.DrillState(e).bit, g.world.Production(e)
+	if bit != 5 || well.zone != 5 || well.struck != 1 {
+		t.Errorf("completing: expect bit 5 zone 5 struck week 1; got bit %d zone %d struck week %d", bit, well.zone, well.struck)
+	}
+	if !g.producing(e) {
+		t.Errorf("expect site 0 producing from zone 5")
+	}
+}
+
+// newTestGame returns a game on the field without starting its state
+// machine, for testing week-boundary logic directly. wells never blow out,
+// so production is predictable.
+func newTestGame(f *field) *game {
+	g := &game{
+		f:           f,
+		drilled:     make(map[entity]int),
+		produced:    make(map[int]int),
+		producedGas: make(map[int]int),
+		systems:     defaultSystems(),
+	}
+	WithSystem("events", nil)(g)
+	return g
+}
+
+// addWell gives the player a well completed in the zone at a site in week 1.
+func addWell(g *game, s site, player entity, zone int) entity {
+	e := g.world.NewEntity()
+	g.world.AddDeed(e, s, player, 1)
+	g.world.DrillState(e).bit = zone
+	gas := !g.f.zone(s, zone)
+	id, _ := g.f.resourceID(gas, s, zone)
+	g.world.Complete(e, &production{zone: zone, struck: 1, gas: gas}, reservoirKey{gas, id})
+	return e
+}
+
+func TestVolumetrics(t *testing.T) {
+	log.SetOutput(ioutil.Discard)
+	defer log.SetOutput(os.Stderr)
+
+	f := &field{
+		height: 1,
+		width:  4,
+		prob:   []int{50, 50, 50, 50},
+		cost:   []int{10, 10, 10, 10},
+		oil:    [][]int{{2}, {2}, {2}, {2}},
+		tax:    []int{100, 100, 100, 100},
+	}
+	ooip := 4 * (barrelsPerSite + 2*barrelsPerDepth)
+
+	// drain half the reservoir with one well, then with four competing
+	// wells. pressure falls with the oil left and wells wear, so a lone
+	// well never gets it all.
+	drain := func(wells int) (weeks int, outputs []int) {
+		g := newTestGame(f)
+		g.week = 1
+		for s := 0; s < wells; s++ {
+			addWell(g, site(s), entity(s+1), 2)
+		}
+		for weeks = 1; g.produced[0] < ooip/2 && weeks < 1000; weeks++ {
+			g.nextWeek()
+			outputs = append(outputs, g.world.Production(g.deed(0)).output)
+			if g.produced[0] > ooip {
+				t.Fatalf("%d wells produced %d barrels from a reservoir of %d", wells, g.produced[0], ooip)
+			}
+		}
+
+		var total int
+		for _, e := range g.world.Tapping(reservoirKey{false, 0}) {
+			total += g.world.Production(e).produced
+		}
+		if total != g.produced[0] {
+			t.Errorf("%d wells: deeds produced %d barrels; reservoir produced %d", wells, total, g.produced[0])
+		}
+		return weeks, outputs
+	}
+
+	alone, outputs := drain(1)
+	if alone == 1000 {
+		t.Fatalf("one well never drained half the reservoir")
+	}
+	// after ramping up, a lone well declines as the reservoir depletes
+	for i := 4; i < len(outputs) && outputs[i] > 0; i++ {
+		if outputs[i] > outputs[i-1] {
+			t.Errorf("week %d output %d; expect decline from %d", i+2, outputs[i], outputs[i-1])
+			break
+		}
+	}
+
+	crowded, _ := drain(4)
+	if crowded >= alone {
+		t.Errorf("four wells drained half the reservoir in %d weeks; one well took %d", crowded, alone)
+	}
+}
+
+func BenchmarkNextWeek(b *testing.B) {
+	log.SetOutput(ioutil.Discard)
+	defer log.SetOutput(os.Stderr)
+
+	for _, bm := range []struct{ height, width, wells int }{
+		{24, 80, 10},
+		{24, 80, 100},
+		{240, 800, 100},
+		{240, 800, 500},
+	} {
+		b.Run(fmt.Sprintf("%dx%d/%d", bm.height, bm.width, bm.wells), func(b *testing.B) {
+			g := newTestGame(generateField(Geology{}, Topology{}, bm.height, bm.width))
+
+			// complete wells in the shallowest zone of the first oil sites
+			for s, zones := range g.f.oil {
+				if len(g.world.Deeds()) == bm.wells {
+					break
+				}
+				if len(zones) > 0 {
+					addWell(g, site(s), 1, zones[0])
+				}
+			}
+
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				g.nextWeek()
+			}
+		})
+	}
+}
+
+func TestWeeklySummary(t *testing.T) {
+	g := New(WithMap(testMap()), WithSystem("events", nil)).(*game)
+	bob := join(t, g, "bob")
+	sue := join(t, g, "sue")
+	makeMove(t, g, bob, done)
+
+	// bob strikes oil at site 2; sue drills a dry bit at site 0
+	makeMove(t, g, bob, 2)
+	makeMove(t, g, bob, yes)
+	makeMove(t, g, bob, 0)
+	makeMove(t, g, bob, 0)
+	makeMove(t, g, bob, yes)
+	makeMove(t, g, bob, done)
+	makeMove(t, g, sue, 0)
+	makeMove(t, g, sue, yes)
+	makeMove(t, g, sue, 0)
+	makeMove(t, g, sue, done)
+	makeMove(t, g, sue, done)
+	makeMove(t, g, bob, 0)
+	awaitWeek(t, g, 2)
+
+	js, _ := json.Marshal(view(t, g, sue))
+	var summary struct {
+		Name      string `json:"name"`
+		Week      int    `json:"week"`
+		Price     int    `json:"price"`
+		PrevPrice int    `json:"prevPrice"`
+		Players   []struct {
+			Name        string `json:"name"`
+			Income      int    `json:"income"`
+			Taxes       int    `json:"taxes"`
+			Drilling    int    `json:"drilling"`
+			Discoveries int    `json:"discoveries"`
+			DryHoles    int    `json:"dryHoles"`
+		} `json:"players"`
+	}
+	json.Unmarshal(js, &summary)
+	if summary.Name != "summary" || summary.Week != 1 || summary.Price != g.price || summary.PrevPrice != g.prevPrice {
+		t.Fatalf("expect summary of week 1 with prices; got %s", js)
+	}
+
+	b, s := summary.Players[0], summary.Players[1]
+	output := g.world.Production(g.deed(2)).output
+	if b.Discoveries != 1 || b.DryHoles != 0 || b.Drilling != 2*30 || b.Taxes != 300 || b.Income != output*g.price/100 {
+		t.Errorf("bob's summary %+v", b)
+	}
+	if s.Discoveries != 0 || s.DryHoles != 1 || s.Drilling != 10 || s.Taxes != 0 {
+		t.Errorf("sue's summary %+v", s)
+	}
+
+	// any move moves on to the survey
+	if v := makeMove(t, g, sue, done); viewName(t, v) != "survey" {
+		t.Errorf("dismissing the summary: expect survey; got %s", viewName(t, v))
+	}
+}
+
+func TestTinyWeek(t *testing.T) {
+	g := New(WithMap(testMap()), WithMode(Realtime), WithWeekLength(time.Nanosecond)).(*game)
+	defer g.Close()
+	if g.weekLength < minWeekLength {
+		t.Fatalf("WithWeekLength(1ns) -> %s; expect at least %s", g.weekLength, minWeekLength)
 	}
+	bob := join(t, g, "bob")
+	makeMove(t, g, bob, done)
+	awaitWeek(t, g, 2)
 }
@@ -0,0 +1,83 @@
+package game
+
+import "math/rand"
+
+const (
+	// mcf of gas originally in place under each site of a reservoir, plus
+	// more for each bit of depth
+	mcfPerSite  = 5000
+	mcfPerDepth = 1000
+	// mcf a gas well can produce per site of its reservoir each week
+	gasRate = 500
+	// mcf of gas that comes up dissolved in each barrel of oil
+	gasOilRatio = 2
+	// the typical price of gas, in cents per mcf
+	gasPrice = 25
+
+	// gas can only be sold by connecting the well to a pipeline no more
+	// than this many sites away, for a fee in dollars plus more for each site
+	maxGathering  = 5
+	hookupFee     = 100
+	hookupPerSite = 40
+
+	// the chance an oil reservoir has a gas cap
+	gasCapChance = 0.4
+	// how many times dry gas is thinned out by prob
+	dryGasDensity = 2
+)
+
+// generateGas lays out gas caps above some of the oil reservoirs, and dry gas
+// reservoirs of their own. oil reservoirs must already be labeled.
+func (f *field) generateGas(gen FieldGenerator) {
+	f.gas = make([][]int, f.height*f.width)
+
+	// gas rises to the top of a trap, so caps sit one bit above the oil
+	for id, members := range f.members {
+		depth := f.oil[members[0]][f.zoneOf(members[0], id)]
+		if depth == minOil || rand.Float64() > gasCapChance {
+			continue
+		}
+		for _, s := range members {
+			if !f.zone(s, depth-1) {
+				f.gas[s] = addZone(f.gas[s], depth-1)
+			}
+		}
+	}
+
+	// dry gas is sparser than oil, and found where the oil isn't
+	spec := LayerSpec{1, minOil, maxOil, 0.1, 0.5, true, f.topo}
+	depths := gen.Layer(f.height, f.width, spec)
+	for i := 0; i < dryGasDensity; i++ {
+		depths = probFilter(depths, f.prob)
+	}
+	for s, depth := range depths {
+		if depth == 0 || f.zone(site(s), depth) {
+			continue
+		}
+		f.gas[s] = addZone(f.gas[s], depth)
+	}
+}
+
+// zoneOf returns the index of the site's pay zone in the given oil reservoir.
+func (f *field) zoneOf(s site, id int) int {
+	for i, label := range f.labels[s] {
+		if label == id {
+			return i
+		}
+	}
+	return -1
+}
+
+// gasZone reports whether the site has a gas zone at the given depth.
+func (f *field) gasZone(s site, depth int) bool {
+	return f.gas != nil && zoneIn(f.gas[s], depth) >= 0
+}
+
+// pipelineAccess reports whether gas from the site can be sold, and what it
+// costs to connect a well there to the pipeline.
+func (f *field) pipelineAccess(s site) (int, bool) {
+	if f.gathering == nil || f.gathering[s] > maxGathering {
+		return 0, false
+	}
+	return hookupFee + hookupPerSite*f.gathering[s], true
+}
@@ -0,0 +1,106 @@
+package game
+
+import (
+	"testing"
+)
+
+func TestGenerateGas(t *testing.T) {
+	for i := 0; i < 10; i++ {
+		f := generateField(Peaks{}, Topology{}, 24, 80)
+		for s, zones := range f.gas {
+			for _, depth := range zones {
+				if f.zone(site(s), depth) {
+					t.Fatalf("site %d has oil and gas at depth %d", s, depth)
+				}
+			}
+		}
+
+		// every gas zone belongs to a reservoir
+		for s, zones := range f.gas {
+			for _, depth := range zones {
+				if _, ok := f.resourceID(true, site(s), depth); !ok {
+					t.Fatalf("gas at site %d depth %d has no reservoir", s, depth)
+				}
+			}
+		}
+	}
+}
+
+func TestGasWell(t *testing.T) {
+	m := Map{
+		Height:  1,
+		Width:   4,
+		Prob:    []int{50, 50, 50, 50},
+		Cost:    []int{10, 10, 10, 10},
+		Tax:     []int{100, 100, 100, 100},
+		Oil:     [][]int{{2}, {2}, nil, nil},
+		Gas:     [][]int{{1}, {1}, nil, {1}},
+		Surface: []int{int(Open), int(Pipeline), int(Open), int(Open)},
+	}
+	if err := m.Validate(); err != nil {
+		t.Fatalf("Validate() -> %s", err)
+	}
+	g := New(WithMap(m), WithMode(Sequential), WithSystem("events", nil)).(*game)
+
+	p := join(t, g, "bob")
+	makeMove(t, g, p, done)
+
+	// strike the gas cap above the oil, next to the pipeline
+	makeMove(t, g, p, 0)
+	makeMove(t, g, p, yes)
+	v := makeMove(t, g, p, 0)
+	if viewName(t, v) != "complete" {
+		t.Fatalf("drilling into a gas cap: expect complete; got %s", viewName(t, v))
+	}
+	if v := makeMove(t, g, p, yes); viewName(t, v) != "connect" {
+		t.Fatalf("completing gas by a pipeline: expect connect; got %s", viewName(t, v))
+	}
+	e := g.deed(0)
+	pnl := g.world.Owner(e).pnl
+	makeMove(t, g, p, yes)
+	fee, _ := g.f.pipelineAccess(0)
+	d := g.world.Production(e)
+	if !d.gas || !d.connected || g.world.Owner(e).pnl != pnl-fee {
+		t.Errorf("expect connected gas well charged %d; got %+v", fee, d)
+	}
+
+	// turn the week by hand while the player's wells view waits
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.price = 0
+	g.nextWeek()
+	if d.output != 0 || d.gasOutput == 0 {
+		t.Errorf("gas well: expect gas and no oil; got %d barrels and %d mcf", d.output, d.gasOutput)
+	}
+	if g.producedGas[0] != d.gasOutput {
+		t.Errorf("gas reservoir produced %d; expect %d", g.producedGas[0], d.gasOutput)
+	}
+	if expect := d.gasOutput*g.gasPrice/100 - 100; g.earnings(e).net() != expect {
+		t.Errorf("connected gas earnings %d; expect %d", g.earnings(e).net(), expect)
+	}
+
+	// flared gas earns nothing
+	d.connected = false
+	if g.earnings(e).net() != -100 {
+		t.Errorf("flared gas earnings %d; expect -100", g.earnings(e).net())
+	}
+}
+
+func TestPipelineAccess(t *testing.T) {
+	f := &field{
+		height:  1,
+		width:   8,
+		surface: []Feature{Pipeline, Open, Open, Open, Open, Open, Open, Open},
+	}
+	f.routeFreight()
+
+	if fee, ok := f.pipelineAccess(0); !ok || fee != hookupFee {
+		t.Errorf("pipelineAccess(0) -> %d, %t; expect %d, true", fee, ok, hookupFee)
+	}
+	if fee, ok := f.pipelineAccess(maxGathering); !ok || fee != hookupFee+maxGathering*hookupPerSite {
+		t.Errorf("pipelineAccess(%d) -> %d, %t", maxGathering, fee, ok)
+	}
+	if _, ok := f.pipelineAccess(maxGathering + 1); ok {
+		t.Errorf("pipelineAccess(%d) -> true; expect beyond reach", maxGathering+1)
+	}
+}
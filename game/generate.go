@@ -0,0 +1,370 @@
+package game
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+)
+
+// LayerSpec describes one layer of a field for a FieldGenerator.
+type LayerSpec struct {
+	// Features is roughly how many peaks, basins or blobs the layer has.
+	Features int
+	// Min and Max bound the layer's values.
+	Min, Max int
+	// Decay lowers each subsequent feature by this fraction.
+	Decay float64
+	// Fuzz is the amplitude of random noise added to every site.
+	Fuzz float64
+	// Inverse flips the layer so that features are low instead of high.
+	Inverse bool
+	// Topology is how the field's sites connect. Generators that measure
+	// distance between sites measure it across the grid, and wrapped
+	// fields have no edges for features to fall off.
+	Topology Topology
+}
+
+// A FieldGenerator lays out the values of a field's layers.
+type FieldGenerator interface {
+	// Layer returns height*width values, row by row, within the spec's bounds.
+	Layer(height, width int, spec LayerSpec) []int
+}
+
+var generators = map[string]FieldGenerator{
+	"":              Peaks{},
+	"peaks":         Peaks{},
+	"noise":         Noise{},
+	"diamondsquare": DiamondSquare{},
+	"voronoi":       Voronoi{},
+	"geology":       Geology{},
+}
+
+// ParseGenerator returns the FieldGenerator with the given name. The empty
+// name is Peaks.
+func ParseGenerator(name string) (FieldGenerator, error) {
+	gen, ok := generators[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown field generator %q", name)
+	}
+	return gen, nil
+}
+
+// Peaks is the classic generator: values fall off logarithmically with
+// Manhattan distance from a few random peaks, giving diamond shaped blobs.
+type Peaks struct{}
+
+func (Peaks) Layer(height, width int, spec LayerSpec) []int {
+	return fill(spec.Topology, height, width, spec.Features, spec.Min, spec.Max, spec.Decay, spec.Fuzz, spec.Inverse)
+}
+
+// Noise generates rolling terrain from a few octaves of Perlin gradient noise.
+// On a wrapped field the noise tiles, meeting itself across the edges.
+type Noise struct{}
+
+// noiseOctaves is how many octaves of noise make up the terrain; each has
+// cells half the size of the one before.
+const noiseOctaves = 3
+
+func (Noise) Layer(height, width int, spec LayerSpec) []int {
+	features := spec.Features
+	if features < 1 {
+		features = 1
+	}
+	// size the lowest octave's cells so there are about as many as features
+	cell := math.Sqrt(float64(height*width) / float64(features))
+	cellY, cellX := cell, cell
+	// the lattice repeats every 256 cells, far beyond an unwrapped field
+	periodY, periodX := 256, 256
+	if spec.Topology.Wrap {
+		// a whole number of cells down and across, repeating at the edges
+		periodY, periodX = cells(height, cell), cells(width, cell)
+		cellY, cellX = float64(height)/float64(periodY), float64(width)/float64(periodX)
+	}
+
+	perm := rand.Perm(256)
+	perm = append(perm, perm...)
+
+	v := make([]float64, height*width)
+	for i := range v {
+		y, x := float64(i/width)/cellY, float64(i%width)/cellX
+		amp, freq := 1.0, 1
+		for octave := 0; octave < noiseOctaves; octave++ {
+			py, px := periodY, periodX
+			if spec.Topology.Wrap {
+				py, px = py*freq, px*freq
+			}
+			v[i] += amp * perlin(perm, x*float64(freq), y*float64(freq), px, py)
+			amp *= 0.5 * (1 - spec.Decay)
+			freq *= 2
+		}
+	}
+	return finish(normalize(v), spec)
+}
+
+// cells returns how many whole cells of about a size fit along n sites, at
+// least one, and few enough that the finest octave's lattice still repeats
+// within 256.
+func cells(n int, size float64) int {
+	c := int(math.Round(float64(n) / size))
+	if most := 256 >> (noiseOctaves - 1); c > most {
+		c = most
+	}
+	if c < 1 {
+		c = 1
+	}
+	return c
+}
+
+// perlin returns 2D gradient noise in roughly [-1, 1] at (x, y). The lattice
+// repeats every px cells across and py down, each at most 256.
+func perlin(perm []int, x, y float64, px, py int) float64 {
+	x0, y0 := math.Floor(x), math.Floor(y)
+	xi, yi := int(x0)%px, int(y0)%py
+	xj, yj := (xi+1)%px, (yi+1)%py
+	xf, yf := x-x0, y-y0
+
+	grad := func(hash int, x, y float64) float64 {
+		// eight gradients around the unit circle
+		switch hash & 7 {
+		case 0:
+			return x + y
+		case 1:
+			return x - y
+		case 2:
+			return -x + y
+		case 3:
+			return -x - y
+		case 4:
+			return x
+		case 5:
+			return -x
+		case 6:
+			return y
+		default:
+			return -y
+		}
+	}
+	fade := func(t float64) float64 {
+		return t * t * t * (t*(t*6-15) + 10)
+	}
+	lerp := func(t, a, b float64) float64 {
+		return a + t*(b-a)
+	}
+
+	aa := perm[perm[xi]+yi]
+	ab := perm[perm[xi]+yj]
+	ba := perm[perm[xj]+yi]
+	bb := perm[perm[xj]+yj]
+
+	u, v := fade(xf), fade(yf)
+	return lerp(v,
+		lerp(u, grad(aa, xf, yf), grad(ba, xf-1, yf)),
+		lerp(u, grad(ab, xf, yf-1), grad(bb, xf-1, yf-1)))
+}
+
+// DiamondSquare generates fractal terrain by midpoint displacement, with
+// Fuzz controlling how rough the terrain is. On a wrapped field the terrain
+// is displaced around a torus, so it meets itself across the edges.
+type DiamondSquare struct{}
+
+// roughness returns how much of its displacement terrain keeps from one
+// scale to the next finer one.
+func roughness(spec LayerSpec) float64 {
+	return math.Min(math.Max(0.4+spec.Fuzz, 0.1), 0.9)
+}
+
+func (d DiamondSquare) Layer(height, width int, spec LayerSpec) []int {
+	// the displacement is all the fuzz this terrain needs
+	smooth := spec
+	smooth.Fuzz = 0
+	if spec.Topology.Wrap {
+		return finish(normalize(d.torus(height, width, roughness(spec))), smooth)
+	}
+
+	// the algorithm works on a square grid of 2^n+1 sites a side
+	n := 1
+	for n+1 < height || n+1 < width {
+		n *= 2
+	}
+	size := n + 1
+	grid := make([]float64, size*size)
+	at := func(y, x int) *float64 { return &grid[y*size+x] }
+
+	for _, c := range [][2]int{{0, 0}, {0, n}, {n, 0}, {n, n}} {
+		*at(c[0], c[1]) = rand.Float64()
+	}
+
+	// rougher terrain keeps more of its displacement at finer scales
+	roughness := roughness(spec)
+	scale := 1.0
+	for step := n; step > 1; step /= 2 {
+		half := step / 2
+
+		// diamond step: the center of each square
+		for y := half; y < size; y += step {
+			for x := half; x < size; x += step {
+				avg := (*at(y-half, x-half) + *at(y-half, x+half) + *at(y+half, x-half) + *at(y+half, x+half)) / 4
+				*at(y, x) = avg + scale*(rand.Float64()-0.5)
+			}
+		}
+
+		// square step: the midpoint of each edge
+		for y := 0; y < size; y += half {
+			for x := (y/half + 1) % 2 * half; x < size; x += step {
+				var sum float64
+				var cnt int
+				for _, d := range [][2]int{{-half, 0}, {half, 0}, {0, -half}, {0, half}} {
+					ny, nx := y+d[0], x+d[1]
+					if ny < 0 || ny >= size || nx < 0 || nx >= size {
+						continue
+					}
+					sum += *at(ny, nx)
+					cnt++
+				}
+				*at(y, x) = sum/float64(cnt) + scale*(rand.Float64()-0.5)
+			}
+		}
+
+		scale *= roughness
+	}
+
+	v := make([]float64, height*width)
+	for i := range v {
+		v[i] = *at(i/width, i%width)
+	}
+	return finish(normalize(v), smooth)
+}
+
+// torus displaces terrain on a grid whose edges meet, a whole number of
+// squares down and across, and stretches it over the field.
+func (DiamondSquare) torus(height, width int, roughness float64) []float64 {
+	// the coarsest squares fit at least twice down and across
+	n := 1
+	for 4*n <= height && 4*n <= width {
+		n *= 2
+	}
+	rows := (height + n - 1) / n * n
+	cols := (width + n - 1) / n * n
+	grid := make([]float64, rows*cols)
+	at := func(y, x int) *float64 { return &grid[(y+rows)%rows*cols+(x+cols)%cols] }
+
+	for y := 0; y < rows; y += n {
+		for x := 0; x < cols; x += n {
+			*at(y, x) = rand.Float64()
+		}
+	}
+
+	scale := 1.0
+	for step := n; step > 1; step /= 2 {
+		half := step / 2
+
+		// diamond step: the center of each square
+		for y := half; y < rows; y += step {
+			for x := half; x < cols; x += step {
+				avg := (*at(y-half, x-half) + *at(y-half, x+half) + *at(y+half, x-half) + *at(y+half, x+half)) / 4
+				*at(y, x) = avg + scale*(rand.Float64()-0.5)
+			}
+		}
+
+		// square step: the midpoint of each edge, whose neighbors are
+		// always there on a torus
+		for y := 0; y < rows; y += half {
+			for x := (y/half + 1) % 2 * half; x < cols; x += step {
+				avg := (*at(y-half, x) + *at(y+half, x) + *at(y, x-half) + *at(y, x+half)) / 4
+				*at(y, x) = avg + scale*(rand.Float64()-0.5)
+			}
+		}
+
+		scale *= roughness
+	}
+
+	// stretch the grid over the field, interpolating between its sites and
+	// across its edges
+	v := make([]float64, height*width)
+	for i := range v {
+		y := float64(i/width) * float64(rows) / float64(height)
+		x := float64(i%width) * float64(cols) / float64(width)
+		y0, x0 := int(y), int(x)
+		fy, fx := y-float64(y0), x-float64(x0)
+		top := *at(y0, x0)*(1-fx) + *at(y0, x0+1)*fx
+		bottom := *at(y0+1, x0)*(1-fx) + *at(y0+1, x0+1)*fx
+		v[i] = top*(1-fy) + bottom*fy
+	}
+	return v
+}
+
+// Voronoi generates flat basins around random seeds, each at its own level,
+// with sharp fault-like boundaries between them. Basins are measured in
+// straight lines rather than grid steps, but reach across wrapped edges.
+type Voronoi struct{}
+
+func (Voronoi) Layer(height, width int, spec LayerSpec) []int {
+	features := spec.Features
+	if features < 1 {
+		features = 1
+	}
+
+	type seed struct {
+		y, x  float64
+		level float64
+	}
+	seeds := make([]seed, features)
+	for i := range seeds {
+		seeds[i] = seed{
+			y:     rand.Float64() * float64(height),
+			x:     rand.Float64() * float64(width),
+			level: rand.Float64() * math.Pow(1-spec.Decay, float64(i)),
+		}
+	}
+
+	v := make([]float64, height*width)
+	for i := range v {
+		y, x := float64(i/width), float64(i%width)
+		nearest := math.Inf(1)
+		for _, s := range seeds {
+			dy, dx := math.Abs(y-s.y), math.Abs(x-s.x)
+			if spec.Topology.Wrap {
+				dy = math.Min(dy, float64(height)-dy)
+				dx = math.Min(dx, float64(width)-dx)
+			}
+			if d := math.Hypot(dy, dx); d < nearest {
+				nearest = d
+				v[i] = s.level
+			}
+		}
+	}
+	return finish(v, spec)
+}
+
+// normalize stretches values linearly to fill [0, 1].
+func normalize(v []float64) []float64 {
+	lo, hi := math.Inf(1), math.Inf(-1)
+	for _, x := range v {
+		lo = math.Min(lo, x)
+		hi = math.Max(hi, x)
+	}
+	out := make([]float64, len(v))
+	if hi == lo {
+		return out
+	}
+	for i, x := range v {
+		out[i] = (x - lo) / (hi - lo)
+	}
+	return out
+}
+
+// finish adds fuzz to values in [0, 1] and scales them into the spec's bounds.
+func finish(v []float64, spec LayerSpec) []int {
+	values := make([]int, len(v))
+	for i, x := range v {
+		x += 2.0 * (rand.Float64() - 0.5) * spec.Fuzz
+		x = math.Min(math.Max(x, 0.0), 1.0)
+
+		values[i] = int(math.Floor(float64(spec.Min) + float64(spec.Max-spec.Min)*x))
+
+		if spec.Inverse {
+			values[i] = spec.Min + spec.Max - values[i]
+		}
+	}
+	return values
+}
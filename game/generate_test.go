@@ -0,0 +1,120 @@
+package game
+
+import "testing"
+
+func TestGenerators(t *testing.T) {
+	specs := []LayerSpec{
+		{1, minProb, maxProb, 0.05, 0.25, false, Topology{}},
+		{1, minOil, maxOil, 0.1, 0.5, true, Topology{}},
+		{7, minCost, maxCost, 0.1, 0.25, true, Topology{}},
+		{15, minTax, maxTax, 0.1, 0.5, false, Topology{}},
+		{7, minCost, maxCost, 0.1, 0.25, false, Topology{Wrap: true}},
+		{15, minTax, maxTax, 0.1, 0, false, Topology{Grid: Hex, Wrap: true}},
+	}
+	sizes := []pt{{24, 80}, {3, 3}, {1, 5}, {17, 17}}
+
+	for name, gen := range generators {
+		for _, size := range sizes {
+			for _, spec := range specs {
+				values := gen.Layer(size.y, size.x, spec)
+				if len(values) != size.y*size.x {
+					t.Errorf("%q %dx%d: len(Layer()) -> %d; expect %d", name, size.y, size.x, len(values), size.y*size.x)
+					continue
+				}
+				for i, v := range values {
+					if v < spec.Min || v > spec.Max {
+						t.Errorf("%q %dx%d: Layer()[%d] -> %d; expect within [%d, %d]", name, size.y, size.x, i, v, spec.Min, spec.Max)
+						break
+					}
+				}
+			}
+		}
+	}
+}
+
+// seams returns how far apart values are across a layer's edges, and
+// between neighbors within it, on average.
+func seams(values []int, height, width int) (seam, within float64) {
+	var seams, neighbors int
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			v := values[y*width+x]
+			right, down := values[y*width+(x+1)%width], values[(y+1)%height*width+x]
+			if x == width-1 {
+				seam += float64(abs(v - right))
+				seams++
+			} else {
+				within += float64(abs(v - right))
+				neighbors++
+			}
+			if y == height-1 {
+				seam += float64(abs(v - down))
+				seams++
+			} else {
+				within += float64(abs(v - down))
+				neighbors++
+			}
+		}
+	}
+	return seam / float64(seams), within / float64(neighbors)
+}
+
+func TestWrappedGenerators(t *testing.T) {
+	spec := LayerSpec{4, minProb, maxProb, 0.05, 0, false, Topology{Wrap: true}}
+	for name, gen := range generators {
+		var seam, within float64
+		for i := 0; i < 20; i++ {
+			s, w := seams(gen.Layer(24, 80, spec), 24, 80)
+			seam += s
+			within += w
+		}
+		// terrain that doesn't tile jumps across the seams
+		if seam > 2*within {
+			t.Errorf("%q: values %.1f apart across the seams, %.1f within; expect the terrain to tile", name, seam/20, within/20)
+		}
+	}
+}
+
+func TestGeneratorsVary(t *testing.T) {
+	spec := LayerSpec{10, minProb, maxProb, 0.05, 0, false, Topology{}}
+	for name, gen := range generators {
+		values := gen.Layer(24, 80, spec)
+		lo, hi := values[0], values[0]
+		for _, v := range values {
+			if v < lo {
+				lo = v
+			}
+			if v > hi {
+				hi = v
+			}
+		}
+		if hi-lo < (maxProb-minProb)/4 {
+			t.Errorf("%q: values span [%d, %d]; expect more varied terrain", name, lo, hi)
+		}
+	}
+}
+
+func TestParseGenerator(t *testing.T) {
+	for _, name := range []string{"", "peaks", "noise", "diamondsquare", "voronoi"} {
+		if _, err := ParseGenerator(name); err != nil {
+			t.Errorf("ParseGenerator(%q) -> %s", name, err)
+		}
+	}
+	if _, err := ParseGenerator("flat"); err == nil {
+		t.Errorf("ParseGenerator(\"flat\") -> nil error; expect unknown generator")
+	}
+}
+
+func TestGenerateField(t *testing.T) {
+	for name, gen := range generators {
+		f := generateField(gen, Topology{}, 24, 80)
+		for s, zones := range f.oil {
+			for i, depth := range zones {
+				if depth < minOil || depth > maxOil || (i > 0 && depth <= zones[i-1]) {
+					t.Errorf("%q: site %d pay zones %v; expect increasing depths within [%d, %d]", name, s, zones, minOil, maxOil)
+					break
+				}
+			}
+		}
+	}
+}
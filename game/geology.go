@@ -0,0 +1,269 @@
+package game
+
+import (
+	"math"
+	"math/rand"
+)
+
+const (
+	// the chance a structure was charged with oil. surveyors can see
+	// structure but not charge, so this is the best odds they can give.
+	chargeChance = 0.65
+	// how much closure a site needs before it can trap oil
+	trapClosure = 0.2
+	// standard deviation of the surveyor's probability estimates
+	surveyError = 8.0
+)
+
+// Geology lays out the subsurface from structural traps: anticlines, salt
+// domes and fault lines. Oil depth, reservoir extent, the surveyor's
+// probability and drilling cost are all derived from the same structures,
+// so a player who learns to read the map can do better than chance. Taxes
+// are still laid out as Peaks.
+type Geology struct{}
+
+func (Geology) Layer(height, width int, spec LayerSpec) []int {
+	return Peaks{}.Layer(height, width, spec)
+}
+
+// a subsurface generator lays out the prob, cost and oil layers together.
+type subsurface interface {
+	subsurface(topo Topology, height, width int) (prob, cost []int, oil [][]int)
+}
+
+// a structure is a geological feature that may trap oil.
+type structure interface {
+	// closure returns how well the structure traps oil at a point, from
+	// zero for no trap to one at the crest.
+	closure(y, x float64) float64
+	// hardness returns how much harder the structure makes drilling at a
+	// point, from zero to one.
+	hardness(y, x float64) float64
+}
+
+// a trap is a structure along with the depths of its reservoirs and
+// whether oil ever migrated into it.
+type trap struct {
+	structure
+	depths  []int
+	charged bool
+}
+
+// anticline is an elongated dome of folded rock.
+type anticline struct {
+	y, x  float64
+	angle float64
+	long  float64
+	short float64
+}
+
+func (a anticline) closure(y, x float64) float64 {
+	dy, dx := y-a.y, x-a.x
+	sin, cos := math.Sincos(a.angle)
+	u := (dx*cos + dy*sin) / a.long
+	v := (-dx*sin + dy*cos) / a.short
+	return math.Max(0, 1-(u*u+v*v))
+}
+
+func (a anticline) hardness(y, x float64) float64 {
+	return 0
+}
+
+// saltDome is a plug of salt that pushed up through the rock. Oil is trapped
+// in a ring around its flanks, but never in the salt itself, which is slow
+// going for the bit.
+type saltDome struct {
+	y, x   float64
+	radius float64
+}
+
+func (d saltDome) closure(y, x float64) float64 {
+	r := math.Hypot(y-d.y, x-d.x) / d.radius
+	if r < 0.4 || r > 1 {
+		return 0
+	}
+	// best on the flank just outside the salt core
+	return 1 - math.Abs(r-0.6)/0.4
+}
+
+func (d saltDome) hardness(y, x float64) float64 {
+	if math.Hypot(y-d.y, x-d.x) < 0.4*d.radius {
+		return 1
+	}
+	return 0
+}
+
+// fault is a fracture across which the rock slipped. Oil collects against it
+// on the upthrown side, and the broken rock near it is hard drilling.
+type fault struct {
+	y, x   float64
+	angle  float64
+	length float64
+	reach  float64
+}
+
+// offsets returns the distance along the fault from its center and the
+// signed distance across it, positive on the upthrown side.
+func (f fault) offsets(y, x float64) (along, across float64) {
+	dy, dx := y-f.y, x-f.x
+	sin, cos := math.Sincos(f.angle)
+	return dx*cos + dy*sin, -dx*sin + dy*cos
+}
+
+func (f fault) closure(y, x float64) float64 {
+	along, across := f.offsets(y, x)
+	if math.Abs(along) > f.length/2 || across < 0 || across > f.reach {
+		return 0
+	}
+	return 1 - across/f.reach
+}
+
+func (f fault) hardness(y, x float64) float64 {
+	along, across := f.offsets(y, x)
+	if math.Abs(along) > f.length/2 {
+		return 0
+	}
+	return math.Max(0, 1-math.Abs(across)/2)
+}
+
+// traps scatters structures over a field, a few more for larger fields.
+func traps(height, width int) []trap {
+	h, w := float64(height), float64(width)
+	scale := math.Sqrt(h * w / (24 * 80))
+	count := func(lo, n int) int {
+		return int(math.Ceil(float64(lo+rand.Intn(n)) * scale))
+	}
+
+	var ts []trap
+	for i := count(2, 3); i > 0; i-- {
+		a := anticline{
+			y:     rand.Float64() * h,
+			x:     rand.Float64() * w,
+			angle: rand.Float64() * math.Pi,
+			long:  (4 + 8*rand.Float64()) * scale,
+			short: (2 + 3*rand.Float64()) * scale,
+		}
+		depth := 2 + rand.Intn(6)
+		depths := []int{depth}
+		// thick folds often stack a second reservoir below the first
+		if rand.Float64() < 0.4 && depth+2 <= maxOil {
+			depths = append(depths, depth+2)
+		}
+		ts = append(ts, trap{a, depths, false})
+	}
+	for i := count(0, 3); i > 0; i-- {
+		d := saltDome{
+			y:      rand.Float64() * h,
+			x:      rand.Float64() * w,
+			radius: (3 + 4*rand.Float64()) * scale,
+		}
+		ts = append(ts, trap{d, []int{3 + rand.Intn(6)}, false})
+	}
+	for i := count(1, 3); i > 0; i-- {
+		f := fault{
+			y:      rand.Float64() * h,
+			x:      rand.Float64() * w,
+			angle:  rand.Float64() * 2 * math.Pi,
+			length: (8 + 16*rand.Float64()) * scale,
+			reach:  (1 + 2*rand.Float64()) * scale,
+		}
+		ts = append(ts, trap{f, []int{2 + rand.Intn(7)}, false})
+	}
+
+	for i := range ts {
+		// every field has at least one charged trap worth finding
+		ts[i].charged = i == 0 || rand.Float64() < chargeChance
+	}
+	return ts
+}
+
+// wrapped returns the structure as seen from a field that wraps around, where
+// it reaches across the seams to the opposite edges.
+func wrapped(s structure, height, width int) structure {
+	var copies []structure
+	for _, dy := range []float64{-1, 0, 1} {
+		for _, dx := range []float64{-1, 0, 1} {
+			copies = append(copies, shifted{s, dy * float64(height), dx * float64(width)})
+		}
+	}
+	return tiled(copies)
+}
+
+// shifted is a structure moved by an offset.
+type shifted struct {
+	structure
+	dy, dx float64
+}
+
+func (s shifted) closure(y, x float64) float64 {
+	return s.structure.closure(y-s.dy, x-s.dx)
+}
+
+func (s shifted) hardness(y, x float64) float64 {
+	return s.structure.hardness(y-s.dy, x-s.dx)
+}
+
+// tiled is copies of one structure, taking the best of them at each point.
+type tiled []structure
+
+func (t tiled) closure(y, x float64) float64 {
+	var c float64
+	for _, s := range t {
+		c = math.Max(c, s.closure(y, x))
+	}
+	return c
+}
+
+func (t tiled) hardness(y, x float64) float64 {
+	var h float64
+	for _, s := range t {
+		h = math.Max(h, s.hardness(y, x))
+	}
+	return h
+}
+
+func (Geology) subsurface(topo Topology, height, width int) (prob, cost []int, oil [][]int) {
+	ts := traps(height, width)
+	if topo.Wrap {
+		for i := range ts {
+			ts[i].structure = wrapped(ts[i].structure, height, width)
+		}
+	}
+
+	// regional rock hardness, before the structures have their say
+	rock := Peaks{}.Layer(height, width, LayerSpec{5 + rand.Intn(5), 0, 100, 0.1, 0.25, true, topo})
+
+	prob = make([]int, height*width)
+	cost = make([]int, height*width)
+	oil = make([][]int, height*width)
+	for i := 0; i < height*width; i++ {
+		y, x := float64(i/width), float64(i%width)
+
+		// the chance that none of the traps closing here were charged
+		dry := 1.0
+		var hard float64
+		for _, t := range ts {
+			hard = math.Max(hard, t.hardness(y, x))
+			if t.closure(y, x) < trapClosure {
+				continue
+			}
+			dry *= 1 - chargeChance
+
+			if !t.charged {
+				continue
+			}
+			for _, depth := range t.depths {
+				oil[i] = addZone(oil[i], depth)
+			}
+		}
+
+		// the surveyor sees the structure but not whether it was charged,
+		// and even the structure only through a glass darkly
+		estimate := 100*(1-dry) + rand.NormFloat64()*surveyError
+		prob[i] = int(math.Min(math.Max(math.Round(estimate), minProb), maxProb))
+
+		h := math.Min(1, 0.6*float64(rock[i])/100+0.6*hard)
+		cost[i] = minCost + int(math.Round(h*float64(maxCost-minCost)))
+	}
+	return prob, cost, oil
+}
@@ -0,0 +1,88 @@
+package game
+
+import (
+	"math"
+	"testing"
+)
+
+func TestAnticline(t *testing.T) {
+	a := anticline{y: 10, x: 10, angle: 0, long: 8, short: 2}
+
+	var tests = []struct {
+		y, x   float64
+		expect float64
+	}{
+		{10, 10, 1},    // crest
+		{10, 14, 0.75}, // along the axis
+		{11, 10, 0.75}, // across the axis
+		{10, 18, 0},    // end of the fold
+		{13, 10, 0},    // off the flank
+	}
+	for _, test := range tests {
+		if c := a.closure(test.y, test.x); math.Abs(c-test.expect) > 1e-9 {
+			t.Errorf("closure(%v, %v) -> %v; expect %v", test.y, test.x, c, test.expect)
+		}
+	}
+}
+
+func TestSaltDome(t *testing.T) {
+	d := saltDome{y: 10, x: 10, radius: 5}
+
+	if c := d.closure(10, 10); c != 0 {
+		t.Errorf("salt core closure -> %v; expect 0", c)
+	}
+	if h := d.hardness(10, 10); h != 1 {
+		t.Errorf("salt core hardness -> %v; expect 1", h)
+	}
+	if c := d.closure(10, 13); c != 1 {
+		t.Errorf("flank closure -> %v; expect 1", c)
+	}
+	if c := d.closure(10, 16); c != 0 {
+		t.Errorf("closure beyond the dome -> %v; expect 0", c)
+	}
+}
+
+func TestFault(t *testing.T) {
+	// an east-west fault upthrown to the south
+	f := fault{y: 10, x: 10, angle: 0, length: 10, reach: 2}
+
+	if c := f.closure(11, 10); c != 0.5 {
+		t.Errorf("upthrown closure -> %v; expect 0.5", c)
+	}
+	if c := f.closure(9, 10); c != 0 {
+		t.Errorf("downthrown closure -> %v; expect 0", c)
+	}
+	if c := f.closure(11, 20); c != 0 {
+		t.Errorf("closure past the end of the fault -> %v; expect 0", c)
+	}
+	if h := f.hardness(9, 10); h != 0.5 {
+		t.Errorf("hardness beside the fault -> %v; expect 0.5", h)
+	}
+}
+
+func TestGeology(t *testing.T) {
+	var oilProb, dryProb, oilSites, drySites int
+	for i := 0; i < 10; i++ {
+		f := generateField(Geology{}, Topology{}, 24, 80)
+		for s := range f.prob {
+			if len(f.oil[s]) > 0 {
+				oilProb += f.prob[s]
+				oilSites++
+			} else {
+				dryProb += f.prob[s]
+				drySites++
+			}
+		}
+	}
+
+	if oilSites == 0 {
+		t.Fatalf("expect oil somewhere in ten geological fields")
+	}
+
+	// the surveyor's estimate is noisy but should tell good ground from bad
+	oilMean := float64(oilProb) / float64(oilSites)
+	dryMean := float64(dryProb) / float64(drySites)
+	if oilMean < dryMean+30 {
+		t.Errorf("mean prob over oil %.1f, over dry sites %.1f; expect a clear difference", oilMean, dryMean)
+	}
+}
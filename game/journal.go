@@ -0,0 +1,413 @@
+package game
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"sync"
+	"time"
+)
+
+// A Change is something that happened in a game: a player's move or the
+// outcome of a system at the turn of the week. Every change to the game's
+// state is applied through one, so replaying a game's changes in order
+// rebuilds its state.
+type Change interface {
+	kind() string
+	apply(g *game)
+}
+
+// An Entry is a change recorded in a game's journal, stamped with the week
+// it happened in.
+type Entry struct {
+	Seq    int       `json:"seq"`
+	Week   int       `json:"week"`
+	Time   time.Time `json:"time"`
+	Kind   string    `json:"kind"`
+	Change Change    `json:"change"`
+}
+
+// the kinds of change, for decoding journals
+var changes = map[string]func() Change{
+	"created":    func() Change { return &Created{} },
+	"joined":     func() Change { return &Joined{} },
+	"weekBegan":  func() Change { return &WeekBegan{} },
+	"prices":     func() Change { return &Prices{} },
+	"blewOut":    func() Change { return &BlewOut{} },
+	"produced":   func() Change { return &Produced{} },
+	"earned":     func() Change { return &Earned{} },
+	"surveyed":   func() Change { return &Surveyed{} },
+	"drilled":    func() Change { return &Drilled{} },
+	"reentered":  func() Change { return &Reentered{} },
+	"workedOver": func() Change { return &WorkedOver{} },
+	"completed":  func() Change { return &Completed{} },
+	"connected":  func() Change { return &Connected{} },
+	"sold":       func() Change { return &Sold{} },
+}
+
+func (e *Entry) UnmarshalJSON(data []byte) error {
+	var raw struct {
+		Seq    int             `json:"seq"`
+		Week   int             `json:"week"`
+		Time   time.Time       `json:"time"`
+		Kind   string          `json:"kind"`
+		Change json.RawMessage `json:"change"`
+	}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+	newChange, ok := changes[raw.Kind]
+	if !ok {
+		return fmt.Errorf("unknown change %q", raw.Kind)
+	}
+	c := newChange()
+	if err := json.Unmarshal(raw.Change, c); err != nil {
+		return fmt.Errorf("decoding %s: %s", raw.Kind, err)
+	}
+	*e = Entry{raw.Seq, raw.Week, raw.Time, raw.Kind, c}
+	return nil
+}
+
+// journal is a game's append-only record of changes.
+type journal struct {
+	mu      sync.Mutex
+	entries []Entry
+	// entries are written out as they're appended, a JSON object a line
+	w io.Writer
+}
+
+// WithJournal writes each entry in the game's journal to w as it's made, as
+// a line of JSON, so the game can be resumed from it.
+func WithJournal(w io.Writer) Option {
+	return func(g *game) {
+		g.journal.w = w
+	}
+}
+
+// ReadJournal reads the entries a game wrote with WithJournal. An entry cut
+// short as it was being written is dropped.
+func ReadJournal(r io.Reader) ([]Entry, error) {
+	var entries []Entry
+	d := json.NewDecoder(r)
+	for {
+		var e Entry
+		err := d.Decode(&e)
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			return entries, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, e)
+	}
+}
+
+func (j *journal) append(week int, c Change) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	e := Entry{len(j.entries), week, time.Now().UTC(), c.kind(), c}
+	j.entries = append(j.entries, e)
+	if j.w == nil {
+		return
+	}
+	if err := json.NewEncoder(j.w).Encode(e); err != nil {
+		log.Printf("writing journal entry %d: %s", e.Seq, err)
+	}
+}
+
+// week returns the entries from one week, or all of them for week zero.
+func (j *journal) week(week int) []Entry {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	var entries []Entry
+	for _, e := range j.entries {
+		if week == 0 || e.Week == week {
+			entries = append(entries, e)
+		}
+	}
+	return entries
+}
+
+// record applies a change to the game and writes it in the journal.
+func (g *game) record(c Change) {
+	c.apply(g)
+	g.journal.append(g.week, c)
+}
+
+// Journal returns the changes recorded in one week of the game, or in the
+// whole game for week zero.
+func (g *game) Journal(week int) []Entry {
+	return g.journal.week(week)
+}
+
+// replay rebuilds a game's state as it stood at the end of a week by applying
+// the journal's changes from the seed up to then. The replayed game has no
+// players connected, so it's only good for looking at.
+func replay(entries []Entry, week int) (*game, error) {
+	r, err := newReplayer(entries)
+	if err != nil {
+		return nil, err
+	}
+	r.through(week)
+	if r.g.week != week {
+		return nil, fmt.Errorf("journal has no week %d", week)
+	}
+	return r.g, nil
+}
+
+// Standings replays the journal to the end of a week and returns every
+// player's wells and profit and loss as they stood then.
+func (g *game) Standings(week int) (View, error) {
+	r, err := replay(g.journal.week(0), week)
+	if err != nil {
+		return nil, err
+	}
+
+	type player struct {
+		Name  string `json:"name"`
+		PNL   int    `json:"pnl"`
+		Wells []well `json:"wells"`
+	}
+	players := make([]player, 0)
+	for _, p := range r.world.Players() {
+		players = append(players, player{r.world.Name(p), r.pnl(p), wellList(r, p)})
+	}
+
+	return struct {
+		Name      string     `json:"name"`
+		Week      int        `json:"week"`
+		Price     int        `json:"price"`
+		GasPrice  int        `json:"gasPrice"`
+		Incidents []Incident `json:"incidents"`
+		Players   []player   `json:"players"`
+	}{"standings", r.week, r.price, r.gasPrice, r.incidents, players}, nil
+}
+
+// Created seeds a game's journal with its mode and field.
+type Created struct {
+	Mode Mode `json:"mode"`
+	Map  Map  `json:"map"`
+}
+
+func (Created) kind() string { return "created" }
+
+func (c Created) apply(g *game) {
+	g.mode = c.Mode
+	g.f = fieldFromMap(c.Map)
+}
+
+// Joined adds a player to the game.
+type Joined struct {
+	Player entity `json:"player"`
+	Name   string `json:"name"`
+}
+
+func (Joined) kind() string { return "joined" }
+
+func (c Joined) apply(g *game) {
+	g.world.Claim(c.Player)
+	g.world.AddPlayer(c.Player)
+	g.world.SetName(c.Player, c.Name)
+}
+
+// WeekBegan turns the week.
+type WeekBegan struct {
+	Week int `json:"week"`
+}
+
+func (WeekBegan) kind() string { return "weekBegan" }
+
+func (c WeekBegan) apply(g *game) {
+	g.week = c.Week
+	for _, player := range g.world.Players() {
+		g.drilled[player] = 0
+	}
+	g.incidents = nil
+}
+
+// Prices sets the week's oil and gas prices.
+type Prices struct {
+	Oil int `json:"oil"`
+	Gas int `json:"gas"`
+}
+
+func (Prices) kind() string { return "prices" }
+
+func (c Prices) apply(g *game) {
+	g.prevPrice, g.prevGasPrice = g.price, g.gasPrice
+	g.price, g.gasPrice = c.Oil, c.Gas
+}
+
+// BlewOut shuts in a well for the week.
+type BlewOut struct {
+	Site site `json:"site"`
+}
+
+func (BlewOut) kind() string { return "blewOut" }
+
+func (c BlewOut) apply(g *game) {
+	g.world.Production(g.deed(c.Site)).shutIn = g.week
+	g.incidents = append(g.incidents, Incident{"blowout", c.Site})
+}
+
+// Produced is a well's oil and gas for the week, drawn from its reservoir.
+type Produced struct {
+	Site      site `json:"site"`
+	Output    int  `json:"output"`
+	GasOutput int  `json:"gasOutput"`
+}
+
+func (Produced) kind() string { return "produced" }
+
+func (c Produced) apply(g *game) {
+	p := g.world.Production(g.deed(c.Site))
+	p.output, p.gasOutput = c.Output, c.GasOutput
+	p.produced += c.Output
+	p.gasProduced += c.GasOutput
+
+	// associated gas doesn't draw down a gas reservoir
+	id, _ := g.f.resourceID(p.gas, c.Site, p.zone)
+	if p.gas {
+		g.producedGas[id] += c.GasOutput
+	} else {
+		g.produced[id] += c.Output
+	}
+}
+
+// Earned credits a well with its income and charges it for transport and
+// taxes.
+type Earned struct {
+	Site      site `json:"site"`
+	Income    int  `json:"income"`
+	GasIncome int  `json:"gasIncome"`
+	Transport int  `json:"transport"`
+	Tax       int  `json:"tax"`
+}
+
+func (Earned) kind() string { return "earned" }
+
+func (c Earned) apply(g *game) {
+	o := g.world.Owner(g.deed(c.Site))
+	o.post(g.week, c.Site, incomeEntry, c.Income)
+	o.post(g.week, c.Site, gasIncomeEntry, c.GasIncome)
+	o.post(g.week, c.Site, transportEntry, -c.Transport)
+	o.post(g.week, c.Site, taxEntry, -c.Tax)
+}
+
+// net returns the income less transport and taxes.
+func (c Earned) net() int {
+	return c.Income + c.GasIncome - c.Transport - c.Tax
+}
+
+// share returns the part of a week's earnings due on a day of the week.
+// shares are taken from the running weekly total so that rounding never
+// loses a cent over the course of a week.
+func (c Earned) share(day int) Earned {
+	share := func(week int) int {
+		return week*day/daysPerWeek - week*(day-1)/daysPerWeek
+	}
+	return Earned{c.Site, share(c.Income), share(c.GasIncome), share(c.Transport), share(c.Tax)}
+}
+
+// Surveyed gives a player the deed to a site.
+type Surveyed struct {
+	Player entity `json:"player"`
+	Site   site   `json:"site"`
+	Deed   entity `json:"deed"`
+}
+
+func (Surveyed) kind() string { return "surveyed" }
+
+func (c Surveyed) apply(g *game) {
+	g.world.Claim(c.Deed)
+	g.world.AddDeed(c.Deed, c.Site, c.Player, g.week)
+}
+
+// Drilled is a bit drilled at a site.
+type Drilled struct {
+	Player entity `json:"player"`
+	Site   site   `json:"site"`
+	Bit    int    `json:"bit"`
+	Cost   int    `json:"cost"`
+}
+
+func (Drilled) kind() string { return "drilled" }
+
+func (c Drilled) apply(g *game) {
+	e := g.deed(c.Site)
+	g.world.DrillState(e).bit = c.Bit
+	g.world.Owner(e).post(g.week, c.Site, drillingEntry, -c.Cost)
+	g.drilled[c.Player]++
+}
+
+// Reentered moves a rig back onto a dry hole.
+type Reentered struct {
+	Player entity `json:"player"`
+	Site   site   `json:"site"`
+	Fee    int    `json:"fee"`
+}
+
+func (Reentered) kind() string { return "reentered" }
+
+func (c Reentered) apply(g *game) {
+	g.world.Owner(g.deed(c.Site)).post(g.week, c.Site, remobilizationEntry, -c.Fee)
+}
+
+// WorkedOver services a producing well.
+type WorkedOver struct {
+	Player entity `json:"player"`
+	Site   site   `json:"site"`
+	Fee    int    `json:"fee"`
+}
+
+func (WorkedOver) kind() string { return "workedOver" }
+
+func (c WorkedOver) apply(g *game) {
+	e := g.deed(c.Site)
+	g.world.Owner(e).post(g.week, c.Site, workoverEntry, -c.Fee)
+	g.world.Production(e).workover = g.week
+}
+
+// Completed makes a deed a well in the zone it was drilled to.
+type Completed struct {
+	Player entity `json:"player"`
+	Site   site   `json:"site"`
+	Zone   int    `json:"zone"`
+	Gas    bool   `json:"gas"`
+}
+
+func (Completed) kind() string { return "completed" }
+
+func (c Completed) apply(g *game) {
+	id, _ := g.f.resourceID(c.Gas, c.Site, c.Zone)
+	p := &production{zone: c.Zone, struck: g.week, gas: c.Gas}
+	g.world.Complete(g.deed(c.Site), p, reservoirKey{c.Gas, id})
+}
+
+// Connected hooks a well up to the pipeline.
+type Connected struct {
+	Player entity `json:"player"`
+	Site   site   `json:"site"`
+	Fee    int    `json:"fee"`
+}
+
+func (Connected) kind() string { return "connected" }
+
+func (c Connected) apply(g *game) {
+	e := g.deed(c.Site)
+	g.world.Owner(e).post(g.week, c.Site, hookupEntry, -c.Fee)
+	g.world.Production(e).connected = true
+}
+
+// Sold sells a deed.
+type Sold struct {
+	Player entity `json:"player"`
+	Site   site   `json:"site"`
+}
+
+func (Sold) kind() string { return "sold" }
+
+func (c Sold) apply(g *game) {
+	g.world.Sell(g.deed(c.Site), g.week)
+}
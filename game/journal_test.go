@@ -0,0 +1,164 @@
+package game
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestJournalReplay(t *testing.T) {
+	g := New(WithMap(testMap()), WithSystem("events", nil)).(*game)
+	p := join(t, g, "bob")
+	makeMove(t, g, p, done)
+
+	// week 1: strike oil at site 2 and complete the well
+	makeMove(t, g, p, 2)
+	makeMove(t, g, p, yes)
+	makeMove(t, g, p, 0)
+	makeMove(t, g, p, 0)
+	if v := makeMove(t, g, p, yes); viewName(t, v) != "wells" {
+		t.Fatalf("completing: expect wells; got %s", viewName(t, v))
+	}
+	makeMove(t, g, p, done)
+	makeMove(t, g, p, 0)
+
+	// week 2: survey another site and pass on it
+	dismissSummary(t, g, p)
+	makeMove(t, g, p, 1)
+	makeMove(t, g, p, no)
+	makeMove(t, g, p, done)
+	makeMove(t, g, p, 0)
+	awaitWeek(t, g, 3)
+
+	week1 := g.Journal(1)
+	var kinds []string
+	for _, e := range week1 {
+		kinds = append(kinds, e.Kind)
+	}
+	expect := []string{"weekBegan", "prices", "surveyed", "drilled", "drilled", "completed"}
+	if !reflect.DeepEqual(kinds, expect) {
+		t.Errorf("week 1 changes %v; expect %v", kinds, expect)
+	}
+
+	// replaying the whole journal rebuilds the live game
+	entries := g.Journal(0)
+	r, err := replay(entries, 3)
+	if err != nil {
+		t.Fatalf("replay() -> %s", err)
+	}
+	if live, replayed := wellList(g, entity(p)), wellList(r, entity(p)); !reflect.DeepEqual(live, replayed) {
+		t.Errorf("replayed wells %+v; expect %+v", replayed, live)
+	}
+	if r.price != g.price || r.produced[0] != g.produced[0] {
+		t.Errorf("replayed price %d and production %d; expect %d and %d", r.price, r.produced[0], g.price, g.produced[0])
+	}
+
+	// an earlier week has the well but not yet its production
+	r, err = replay(entries, 1)
+	if err != nil {
+		t.Fatalf("replay() -> %s", err)
+	}
+	if p := r.world.Production(r.deed(2)); p == nil || p.produced != 0 {
+		t.Errorf("week 1 well %+v; expect completed without production", p)
+	}
+	if _, err := replay(entries, 4); err == nil {
+		t.Errorf("replaying a week that hasn't happened: expect error")
+	}
+
+	// the journal survives encoding
+	js, err := json.Marshal(entries)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var decoded []Entry
+	if err := json.Unmarshal(js, &decoded); err != nil {
+		t.Fatalf("decoding journal: %s", err)
+	}
+	r, err = replay(decoded, 3)
+	if err != nil {
+		t.Fatalf("replaying decoded journal: %s", err)
+	}
+	if live, replayed := wellList(g, entity(p)), wellList(r, entity(p)); !reflect.DeepEqual(live, replayed) {
+		t.Errorf("decoded replay wells %+v; expect %+v", replayed, live)
+	}
+
+	if _, err := replay(entries[1:], 3); err == nil {
+		t.Errorf("replaying without the seed: expect error")
+	}
+}
+
+func TestStandings(t *testing.T) {
+	g := New(WithMap(testMap())).(*game)
+	p := join(t, g, "sue")
+	makeMove(t, g, p, done)
+	makeMove(t, g, p, 0)
+	makeMove(t, g, p, yes)
+	makeMove(t, g, p, 0)
+	makeMove(t, g, p, done)
+
+	v, err := g.Standings(1)
+	if err != nil {
+		t.Fatalf("Standings(1) -> %s", err)
+	}
+	js, _ := json.Marshal(v)
+	var standings struct {
+		Players []struct {
+			Name string `json:"name"`
+			PNL  int    `json:"pnl"`
+		} `json:"players"`
+	}
+	json.Unmarshal(js, &standings)
+	if len(standings.Players) != 1 || standings.Players[0].Name != "sue" || standings.Players[0].PNL != -testMap().Cost[0] {
+		t.Errorf("standings %+v; expect sue down the cost of one bit", standings)
+	}
+}
+
+func TestTimeline(t *testing.T) {
+	g := New(WithMap(testMap()), WithSystem("events", nil)).(*game)
+	bob := join(t, g, "bob")
+	sue := join(t, g, "sue")
+	makeMove(t, g, bob, done)
+
+	// week 1: bob drills a dry bit at site 0 and sells it; sue passes
+	makeMove(t, g, bob, 0)
+	makeMove(t, g, bob, yes)
+	makeMove(t, g, bob, 0)
+	makeMove(t, g, bob, done)
+	makeMove(t, g, bob, 0)
+	makeMove(t, g, bob, done)
+	makeMove(t, g, sue, 4)
+	makeMove(t, g, sue, no)
+	makeMove(t, g, sue, done)
+	makeMove(t, g, bob, 0)
+	awaitWeek(t, g, 2)
+
+	tl, err := g.Timeline()
+	if err != nil {
+		t.Fatalf("Timeline() -> %s", err)
+	}
+	if len(tl.Weeks) != 2 || tl.Field.Width != 3 {
+		t.Fatalf("timeline of %d weeks on a field %d wide; expect 2 weeks, 3 wide", len(tl.Weeks), tl.Field.Width)
+	}
+	w1 := tl.Weeks[0]
+	var kinds []string
+	for _, e := range w1.Players[0].Moves {
+		kinds = append(kinds, e.Kind)
+	}
+	if expect := []string{"surveyed", "drilled", "sold"}; !reflect.DeepEqual(kinds, expect) {
+		t.Errorf("bob's week 1 moves %v; expect %v", kinds, expect)
+	}
+	if len(w1.Players[1].Moves) != 1 || w1.Players[0].PNL != -testMap().Cost[0] {
+		t.Errorf("week 1 players %+v", w1.Players)
+	}
+
+	f, err := g.Frame(1)
+	if err != nil {
+		t.Fatalf("Frame(1) -> %s", err)
+	}
+	if f.Weeks != 2 || len(f.Deeds) != 2 || f.Deeds[0].Player != "bob" || f.Deeds[0].Sold != 1 || f.Deeds[0].Bit != 1 {
+		t.Errorf("week 1 frame %+v", f)
+	}
+	if _, err := g.Frame(3); err == nil {
+		t.Errorf("Frame(3) before week 3: expect error")
+	}
+}
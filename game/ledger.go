@@ -0,0 +1,55 @@
+package game
+
+import "sort"
+
+// the kinds of ledger entry
+const (
+	drillingEntry       = "drilling"
+	remobilizationEntry = "remobilization"
+	workoverEntry       = "workover"
+	hookupEntry         = "hookup"
+	incomeEntry         = "income"
+	gasIncomeEntry      = "gas income"
+	transportEntry      = "transport"
+	taxEntry            = "tax"
+)
+
+// LedgerEntry is an item in a deed's account: money in is positive and
+// money out negative.
+type LedgerEntry struct {
+	Week   int    `json:"week"`
+	Site   site   `json:"site"`
+	Type   string `json:"type"`
+	Amount int    `json:"amount"`
+}
+
+// post adds an amount to the deed's account. amounts of the same type in the
+// same week are one entry, so bits drilled and real-time income accrued by
+// the day add up.
+func (o *owner) post(week int, s site, kind string, amount int) {
+	if amount == 0 {
+		return
+	}
+	o.pnl += amount
+	for i := len(o.ledger) - 1; i >= 0 && o.ledger[i].Week == week; i-- {
+		if o.ledger[i].Type == kind {
+			o.ledger[i].Amount += amount
+			return
+		}
+	}
+	o.ledger = append(o.ledger, LedgerEntry{week, s, kind, amount})
+}
+
+// Ledger returns the itemized accounts of all the player's deeds, by week.
+func (g *game) Ledger(playerID int) []LedgerEntry {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	entries := make([]LedgerEntry, 0)
+	for _, e := range g.world.Owned(entity(playerID)) {
+		entries = append(entries, g.world.Owner(e).ledger...)
+	}
+	sort.SliceStable(entries, func(i, j int) bool {
+		return entries[i].Week < entries[j].Week
+	})
+	return entries
+}
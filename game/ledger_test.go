@@ -0,0 +1,69 @@
+package game
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestLedger(t *testing.T) {
+	f := &field{
+		height: 1,
+		width:  2,
+		prob:   []int{50, 50},
+		cost:   []int{10, 10},
+		oil:    [][]int{{2}, nil},
+		tax:    []int{100, 100},
+	}
+	g := newTestGame(f)
+	p := g.world.NewEntity()
+	g.record(Joined{p, "bob"})
+	g.record(WeekBegan{1})
+	g.record(Prices{100, 0})
+
+	g.record(Surveyed{p, 0, g.world.NewEntity()})
+	g.record(Drilled{p, 0, 1, 10})
+	g.record(Drilled{p, 0, 2, 10})
+	g.record(Completed{p, 0, 2, false})
+	g.record(Surveyed{p, 1, g.world.NewEntity()})
+	g.record(Drilled{p, 1, 1, 10})
+
+	g.record(WeekBegan{2})
+	g.record(Produced{0, 150, 300})
+	g.record(g.earnings(g.deed(0)))
+
+	expect := []LedgerEntry{
+		{1, 0, drillingEntry, -20},
+		{1, 1, drillingEntry, -10},
+		{2, 0, incomeEntry, 150},
+		{2, 0, taxEntry, -100},
+	}
+	ledger := g.Ledger(int(p))
+	if !reflect.DeepEqual(ledger, expect) {
+		t.Errorf("ledger %+v; expect %+v", ledger, expect)
+	}
+
+	// the ledger accounts for every dollar of P&L
+	total := 0
+	for _, e := range ledger {
+		total += e.Amount
+	}
+	if total != g.pnl(p) {
+		t.Errorf("ledger totals %d; P&L %d", total, g.pnl(p))
+	}
+}
+
+func TestEarnedShare(t *testing.T) {
+	week := Earned{Site: 3, Income: 1000, GasIncome: 99, Transport: 55, Tax: 101}
+	var sum Earned
+	for day := 1; day <= daysPerWeek; day++ {
+		share := week.share(day)
+		sum.Income += share.Income
+		sum.GasIncome += share.GasIncome
+		sum.Transport += share.Transport
+		sum.Tax += share.Tax
+	}
+	sum.Site = 3
+	if sum != week {
+		t.Errorf("daily shares sum to %+v; expect %+v", sum, week)
+	}
+}
@@ -0,0 +1,160 @@
+package game
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"time"
+)
+
+// ErrClosed is returned for joins, moves and views of a closed game.
+var ErrClosed = errors.New("game closed")
+
+// minIdleTimeout is the shortest a game may sit idle before it's abandoned.
+const minIdleTimeout = 10 * time.Millisecond
+
+// WithIdleTimeout sets how long a game may go without a player making a move
+// or looking at it before it's abandoned and closes itself. Timeouts that
+// aren't positive leave the default in place.
+func WithIdleTimeout(d time.Duration) Option {
+	return func(g *game) {
+		if d <= 0 {
+			return
+		}
+		if d < minIdleTimeout {
+			d = minIdleTimeout
+		}
+		g.idleTimeout = d
+	}
+}
+
+// Close ends the game and waits for its goroutines to finish. A closed game
+// can still be looked back on, but no longer played.
+func (g *game) Close() error {
+	g.shutdown()
+	g.running.Wait()
+	return nil
+}
+
+// Done returns a channel that's closed when the game is.
+func (g *game) Done() <-chan struct{} {
+	return g.done
+}
+
+func (g *game) shutdown() {
+	g.closing.Do(func() {
+		close(g.done)
+		g.cancel()
+		stats.Add("Closed", 1)
+	})
+}
+
+// closed reports whether the game has been closed.
+func (g *game) closed() bool {
+	select {
+	case <-g.done:
+		return true
+	default:
+		return false
+	}
+}
+
+// touch notes that a player is still about.
+func (g *game) touch() {
+	g.active.Store(time.Now().UnixNano())
+}
+
+// watch closes the game once nobody has moved or looked at it for the idle
+// timeout.
+func (g *game) watch() {
+	defer g.running.Done()
+	check := time.NewTicker(g.idleTimeout / 4)
+	defer check.Stop()
+
+	for {
+		select {
+		case now := <-check.C:
+			idle := now.Sub(time.Unix(0, g.active.Load()))
+			if idle < g.idleTimeout {
+				break
+			}
+			log.Printf("closing game abandoned in week %d after %s", g.locked(func(g *game) View { return g.week }), idle.Round(time.Second))
+			stats.Add("Abandoned", 1)
+			g.shutdown()
+			return
+		case <-g.done:
+			return
+		}
+	}
+}
+
+// Restore rebuilds a closed game from its journal, for looking back on an
+// archived game.
+func Restore(entries []Entry) (Game, error) {
+	r, err := newReplayer(entries)
+	if err != nil {
+		return nil, err
+	}
+	r.through(r.weeks())
+	r.g.journal.entries = entries
+	// the game was shut down before it was archived, so closing it again
+	// does nothing
+	r.g.done = make(chan struct{})
+	r.g.closing.Do(func() { close(r.g.done) })
+	return r.g, nil
+}
+
+// Resume rebuilds an Async game from the journal it was writing when it was
+// interrupted, say by the server restarting, and carries on playing it. It
+// takes the options the game was created with; its mode and field come from
+// the journal. The week in play keeps its deadline, and players who'd already
+// moved in it pick up at their wells, leaving any well they were drilling
+// where it stopped.
+func Resume(entries []Entry, opts ...Option) (Game, error) {
+	if len(entries) == 0 || entries[0].Kind != "created" {
+		return nil, fmt.Errorf("journal has no seed")
+	}
+	g := newGame(opts)
+	for _, e := range entries {
+		e.Change.apply(g)
+	}
+	g.journal.entries = entries
+	if g.mode != Async {
+		g.cancel()
+		return nil, fmt.Errorf("only async games can be resumed")
+	}
+	g.setDefaults()
+
+	var began time.Time
+	for _, e := range entries {
+		if e.Kind == "weekBegan" {
+			began = e.Time
+		}
+	}
+	for _, playerID := range g.world.Players() {
+		g.seat(playerID)
+		if !g.moved(playerID) {
+			g.world.SetSurveyor(playerID)
+		}
+	}
+	state := lobby
+	if g.week > 0 {
+		state = play
+		g.resumed = true
+		g.deadline = began.Add(g.weekLength)
+	}
+	g.start(state)
+
+	stats.Add("Resumed", 1)
+	return g, nil
+}
+
+// moved reports whether a player has made a move this week.
+func (g *game) moved(playerID entity) bool {
+	for _, e := range g.journal.week(g.week) {
+		if m, ok := e.Change.(move); ok && m.by() == playerID {
+			return true
+		}
+	}
+	return false
+}
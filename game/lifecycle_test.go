@@ -0,0 +1,213 @@
+package game
+
+import (
+	"bytes"
+	"context"
+	"reflect"
+	"runtime"
+	"testing"
+	"time"
+)
+
+// awaitGoroutines waits for the number of goroutines to fall back to a
+// baseline.
+func awaitGoroutines(t *testing.T, baseline int) {
+	deadline := time.Now().Add(time.Second)
+	for time.Now().Before(deadline) {
+		if runtime.NumGoroutine() <= baseline {
+			return
+		}
+		time.Sleep(time.Millisecond)
+	}
+	buf := make([]byte, 1<<16)
+	t.Fatalf("%d goroutines; expect %d\n%s", runtime.NumGoroutine(), baseline, buf[:runtime.Stack(buf, true)])
+}
+
+func TestClose(t *testing.T) {
+	for _, mode := range []Mode{Simultaneous, Sequential, Realtime, Async} {
+		baseline := runtime.NumGoroutine()
+
+		g := New(WithMap(testMap()), WithMode(mode), WithWeekLength(time.Hour)).(*game)
+		bob := join(t, g, "bob")
+		sue := join(t, g, "sue")
+		makeMove(t, g, bob, done)
+		// bob goes to the report, while sue looks on or waits her turn
+		if mode == Sequential {
+			awaitTurn(t, g, bob)
+		}
+		makeMove(t, g, bob, 2)
+		view(t, g, sue)
+
+		if err := g.Close(); err != nil {
+			t.Fatalf("mode %d: Close() -> %s", mode, err)
+		}
+		select {
+		case <-g.Done():
+		default:
+			t.Errorf("mode %d: Done() open after Close", mode)
+		}
+		awaitGoroutines(t, baseline)
+
+		ctx := context.Background()
+		if _, err := g.Move(ctx, bob, yes); err != ErrClosed {
+			t.Errorf("mode %d: Move after Close -> %v; expect ErrClosed", mode, err)
+		}
+		if _, err := g.View(ctx, sue); err != ErrClosed {
+			t.Errorf("mode %d: View after Close -> %v; expect ErrClosed", mode, err)
+		}
+		if _, err := g.Status(ctx); err != ErrClosed {
+			t.Errorf("mode %d: Status after Close -> %v; expect ErrClosed", mode, err)
+		}
+		if _, err := g.Join(ctx, "tom"); err != ErrClosed {
+			t.Errorf("mode %d: Join after Close -> %v; expect ErrClosed", mode, err)
+		}
+		if len(g.Journal(1)) == 0 {
+			t.Errorf("mode %d: closed game has no journal", mode)
+		}
+
+		// closing twice is harmless
+		g.Close()
+	}
+}
+
+func TestCloseNotifying(t *testing.T) {
+	baseline := runtime.NumGoroutine()
+
+	// nobody reads the notifications, so they're stuck until the game closes
+	g := New(WithMap(testMap()), WithMode(Async), WithNotifier(make(chanNotifier))).(*game)
+	bob := join(t, g, "bob")
+	makeMove(t, g, bob, done)
+	g.Close()
+	awaitGoroutines(t, baseline)
+}
+
+func TestIdleGame(t *testing.T) {
+	baseline := runtime.NumGoroutine()
+
+	g := New(WithMap(testMap()), WithIdleTimeout(40*time.Millisecond)).(*game)
+	bob := join(t, g, "bob")
+	makeMove(t, g, bob, done)
+
+	// keeping an eye on the game keeps it open
+	for i := 0; i < 6; i++ {
+		time.Sleep(10 * time.Millisecond)
+		view(t, g, bob)
+	}
+	if g.closed() {
+		t.Fatalf("game closed while bob was playing")
+	}
+
+	select {
+	case <-g.Done():
+	case <-time.After(time.Second):
+		t.Fatalf("abandoned game never closed")
+	}
+	awaitGoroutines(t, baseline)
+}
+
+func TestRestore(t *testing.T) {
+	g := New(WithMap(testMap()), WithSystem("events", nil)).(*game)
+	bob := join(t, g, "bob")
+	makeMove(t, g, bob, done)
+
+	// bob drills a bit at site 2 and gives up
+	makeMove(t, g, bob, 2)
+	makeMove(t, g, bob, yes)
+	makeMove(t, g, bob, 0)
+	makeMove(t, g, bob, done)
+	makeMove(t, g, bob, done)
+	makeMove(t, g, bob, done)
+	awaitWeek(t, g, 2)
+	g.Close()
+
+	restored, err := Restore(g.Journal(0))
+	if err != nil {
+		t.Fatalf("Restore() -> %s", err)
+	}
+	if !reflect.DeepEqual(restored.Ledger(bob), g.Ledger(bob)) {
+		t.Errorf("restored ledger %v; expect %v", restored.Ledger(bob), g.Ledger(bob))
+	}
+	expect, _ := g.Standings(2)
+	if standings, err := restored.Standings(2); err != nil || !reflect.DeepEqual(standings, expect) {
+		t.Errorf("restored Standings(2) -> %+v, %v; expect %+v", standings, err, expect)
+	}
+	if _, err := restored.View(context.Background(), bob); err != ErrClosed {
+		t.Errorf("View of a restored game -> %v; expect ErrClosed", err)
+	}
+
+	// an admin may close an archived game again
+	if err := restored.Close(); err != nil {
+		t.Errorf("Close() of a restored game -> %s", err)
+	}
+}
+
+func TestResume(t *testing.T) {
+	var written bytes.Buffer
+	g := New(WithMap(testMap()), WithMode(Async), WithJournal(&written), WithSystem("events", nil)).(*game)
+	bob := join(t, g, "bob")
+	sue := join(t, g, "sue")
+	makeMove(t, g, bob, done)
+
+	// bob surveys site 2 and passes on drilling it before the server stops
+	makeMove(t, g, bob, 2)
+	makeMove(t, g, bob, no)
+	g.Close()
+
+	entries, err := ReadJournal(&written)
+	if err != nil {
+		t.Fatalf("ReadJournal() -> %s", err)
+	}
+	if n := len(g.Journal(0)); len(entries) != n || entries[n-1].Kind != "surveyed" {
+		t.Fatalf("written journal %+v; expect %d entries ending in bob's survey", entries, n)
+	}
+	var resumedJournal bytes.Buffer
+	resumed, err := Resume(entries, WithJournal(&resumedJournal), WithSystem("events", nil))
+	if err != nil {
+		t.Fatalf("Resume() -> %s", err)
+	}
+	r := resumed.(*game)
+	if r.week != 1 || r.deadline.Sub(g.deadline).Abs() > time.Second {
+		t.Errorf("resumed week %d due %s; expect week 1 due %s", r.week, r.deadline, g.deadline)
+	}
+
+	// bob carries on at his wells, and sue hasn't surveyed yet
+	if name := viewName(t, view(t, r, bob)); name != "wells" {
+		t.Errorf("bob resumed in %s; expect wells", name)
+	}
+	if name := viewName(t, view(t, r, sue)); name != "survey" {
+		t.Errorf("sue resumed in %s; expect survey", name)
+	}
+	makeMove(t, r, bob, done)
+	makeMove(t, r, sue, 1)
+	makeMove(t, r, sue, no)
+	makeMove(t, r, sue, done)
+	awaitWeek(t, r, 2)
+	r.Close()
+
+	// the resumed game goes on writing its journal
+	more, err := ReadJournal(&resumedJournal)
+	if err != nil {
+		t.Fatalf("ReadJournal() -> %s", err)
+	}
+	if len(more) == 0 || more[0].Seq != len(entries) || more[0].Kind != "surveyed" {
+		t.Errorf("resumed journal %+v; expect it to carry on from entry %d with sue's survey", more, len(entries))
+	}
+
+	if _, err := Resume(New(WithMap(testMap())).Journal(0)); err == nil {
+		t.Errorf("Resume() of a simultaneous game; expect an error")
+	}
+}
+
+func TestTinyIdleTimeout(t *testing.T) {
+	baseline := runtime.NumGoroutine()
+	for _, d := range []time.Duration{-time.Second, time.Nanosecond} {
+		g := New(WithMap(testMap()), WithIdleTimeout(d)).(*game)
+		if g.idleTimeout < minIdleTimeout {
+			t.Errorf("WithIdleTimeout(%s) -> %s; expect at least %s", d, g.idleTimeout, minIdleTimeout)
+		}
+		g.Close()
+	}
+	g := New(WithMap(testMap()), WithMode(Async), WithWeekLength(time.Nanosecond)).(*game)
+	g.Close()
+	awaitGoroutines(t, baseline)
+}
@@ -0,0 +1,324 @@
+package game
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"io"
+)
+
+// Map is the portable form of a game's field, for designing puzzle maps and
+// replaying famous fields. Layers are stored row by row; Oil and Gas list
+// each site's pay zone depths, shallowest first, and Surface each site's
+// Feature. Maps without gas have none, and maps without a surface have no
+// towns and ship oil for free.
+type Map struct {
+	Name     string   `json:"name"`
+	Height   int      `json:"height"`
+	Width    int      `json:"width"`
+	Topology Topology `json:"topology"`
+	Prob     []int    `json:"prob"`
+	Cost     []int    `json:"cost"`
+	Tax      []int    `json:"tax"`
+	Oil      [][]int  `json:"oil"`
+	Gas      [][]int  `json:"gas,omitempty"`
+	Surface  []int    `json:"surface,omitempty"`
+}
+
+// Region is a rectangle of sites on a map.
+type Region struct {
+	Y      int `json:"y"`
+	X      int `json:"x"`
+	Height int `json:"height"`
+	Width  int `json:"width"`
+}
+
+// Edit sets every site of a region in one of a map's layers to a value. For
+// the oil and gas layers the value is a single pay zone depth, or zero for
+// none.
+type Edit struct {
+	Layer string `json:"layer"`
+	Region
+	Value int `json:"value"`
+}
+
+// Layers are the names of a map's layers.
+var Layers = []string{"prob", "cost", "tax", "oil", "gas", "surface"}
+
+// bounds returns the limits of a layer's values.
+func bounds(layer string) (min, max int, err error) {
+	switch layer {
+	case "prob":
+		return minProb, maxProb, nil
+	case "cost":
+		return minCost, maxCost, nil
+	case "tax":
+		return minTax, maxTax, nil
+	case "oil", "gas":
+		return minOil, maxOil, nil
+	case "surface":
+		return int(minFeature), int(maxFeature), nil
+	}
+	return 0, 0, fmt.Errorf("unknown layer %q", layer)
+}
+
+// zones returns one of the map's layers of pay zones, or nil if the layer
+// isn't one. A missing gas layer is filled with none.
+func (m *Map) zones(layer string) *[][]int {
+	switch layer {
+	case "oil":
+		return &m.Oil
+	case "gas":
+		if m.Gas == nil {
+			m.Gas = make([][]int, m.Height*m.Width)
+		}
+		return &m.Gas
+	}
+	return nil
+}
+
+// values returns one of the map's numeric layers. A missing surface is
+// filled with open ground.
+func (m *Map) values(layer string) ([]int, error) {
+	switch layer {
+	case "surface":
+		if m.Surface == nil {
+			m.Surface = make([]int, m.Height*m.Width)
+		}
+		return m.Surface, nil
+	case "prob":
+		return m.Prob, nil
+	case "cost":
+		return m.Cost, nil
+	case "tax":
+		return m.Tax, nil
+	}
+	return nil, fmt.Errorf("unknown layer %q", layer)
+}
+
+// Validate checks that the map's layers fit its dimensions and bounds.
+func (m *Map) Validate() error {
+	if m.Height < 1 || m.Width < 1 {
+		return fmt.Errorf("map %q: invalid size %dx%d", m.Name, m.Height, m.Width)
+	}
+	if m.Topology.Grid == Hex && m.Topology.Wrap && m.Height%2 != 0 {
+		return fmt.Errorf("map %q: wrapped hex grid has odd height %d", m.Name, m.Height)
+	}
+	n := m.Height * m.Width
+
+	for _, layer := range Layers {
+		min, max, _ := bounds(layer)
+		if (layer == "surface" && m.Surface == nil) || (layer == "gas" && m.Gas == nil) {
+			continue
+		}
+		if layer == "oil" || layer == "gas" {
+			layers := *m.zones(layer)
+			if len(layers) != n {
+				return fmt.Errorf("map %q: %s has %d sites; expect %d", m.Name, layer, len(layers), n)
+			}
+			for s, zones := range layers {
+				for i, depth := range zones {
+					if depth < min || depth > max || (i > 0 && depth <= zones[i-1]) {
+						return fmt.Errorf("map %q: %s at site %d must be increasing depths from %d to %d", m.Name, layer, s, min, max)
+					}
+					if layer == "gas" && zoneIn(m.Oil[s], depth) >= 0 {
+						return fmt.Errorf("map %q: site %d has oil and gas at depth %d", m.Name, s, depth)
+					}
+				}
+			}
+			continue
+		}
+
+		values, _ := m.values(layer)
+		if len(values) != n {
+			return fmt.Errorf("map %q: %s has %d sites; expect %d", m.Name, layer, len(values), n)
+		}
+		for s, v := range values {
+			if v < min || v > max {
+				return fmt.Errorf("map %q: %s at site %d is %d; expect %d to %d", m.Name, layer, s, v, min, max)
+			}
+		}
+	}
+	return nil
+}
+
+// Apply makes an edit to the map.
+func (m *Map) Apply(e Edit) error {
+	min, max, err := bounds(e.Layer)
+	if err != nil {
+		return err
+	}
+	if e.Y < 0 || e.X < 0 || e.Height < 1 || e.Width < 1 || e.Y+e.Height > m.Height || e.X+e.Width > m.Width {
+		return fmt.Errorf("region %+v is outside the %dx%d map", e.Region, m.Height, m.Width)
+	}
+	zones := m.zones(e.Layer)
+	if (e.Value < min || e.Value > max) && !(zones != nil && e.Value == 0) {
+		return fmt.Errorf("%s value %d; expect %d to %d", e.Layer, e.Value, min, max)
+	}
+
+	values, _ := m.values(e.Layer)
+	for y := e.Y; y < e.Y+e.Height; y++ {
+		for x := e.X; x < e.X+e.Width; x++ {
+			s := y*m.Width + x
+			if zones == nil {
+				values[s] = e.Value
+			} else if e.Value == 0 {
+				(*zones)[s] = nil
+			} else {
+				(*zones)[s] = []int{e.Value}
+			}
+		}
+	}
+	return nil
+}
+
+// EncodeLayer writes one of the map's layers as a 16-bit grayscale PNG. Pixel
+// values are the layer's values, except in the oil and gas layers where bit d
+// is set for a pay zone at depth d.
+func (m *Map) EncodeLayer(w io.Writer, layer string) error {
+	if _, _, err := bounds(layer); err != nil {
+		return err
+	}
+
+	img := image.NewGray16(image.Rect(0, 0, m.Width, m.Height))
+	for s := 0; s < m.Height*m.Width; s++ {
+		var v uint16
+		if zones := m.zones(layer); zones != nil {
+			for _, depth := range (*zones)[s] {
+				v |= 1 << uint(depth)
+			}
+		} else {
+			values, _ := m.values(layer)
+			v = uint16(values[s])
+		}
+		img.SetGray16(s%m.Width, s/m.Width, color.Gray16{v})
+	}
+	return png.Encode(w, img)
+}
+
+// DecodeLayer replaces one of the map's layers with a PNG in the form written
+// by EncodeLayer. The image must be the same size as the map.
+func (m *Map) DecodeLayer(r io.Reader, layer string) error {
+	min, max, err := bounds(layer)
+	if err != nil {
+		return err
+	}
+	img, err := png.Decode(r)
+	if err != nil {
+		return err
+	}
+	size := img.Bounds().Size()
+	if size.X != m.Width || size.Y != m.Height {
+		return fmt.Errorf("%s layer is %dx%d; expect %dx%d", layer, size.Y, size.X, m.Height, m.Width)
+	}
+
+	n := m.Height * m.Width
+	values := make([]int, n)
+	zones := make([][]int, n)
+	for s := 0; s < n; s++ {
+		pt := img.Bounds().Min.Add(image.Pt(s%m.Width, s/m.Width))
+		v := int(color.Gray16Model.Convert(img.At(pt.X, pt.Y)).(color.Gray16).Y)
+		if layer != "oil" && layer != "gas" {
+			if v < min || v > max {
+				return fmt.Errorf("%s at site %d is %d; expect %d to %d", layer, s, v, min, max)
+			}
+			values[s] = v
+			continue
+		}
+		if outside := v &^ (1<<uint(max+1) - 1<<uint(min)); outside != 0 {
+			return fmt.Errorf("%s at site %d has pay zones at bits %#x; expect depths %d to %d", layer, s, outside, min, max)
+		}
+		for depth := min; depth <= max; depth++ {
+			if v&(1<<uint(depth)) != 0 {
+				zones[s] = append(zones[s], depth)
+			}
+		}
+	}
+
+	switch layer {
+	case "prob":
+		m.Prob = values
+	case "cost":
+		m.Cost = values
+	case "tax":
+		m.Tax = values
+	case "oil":
+		m.Oil = zones
+	case "gas":
+		m.Gas = zones
+	case "surface":
+		m.Surface = values
+	}
+	return nil
+}
+
+// Copy returns a copy of the map that shares none of its layers.
+func (m Map) Copy() Map {
+	c := m
+	c.Prob = append([]int(nil), m.Prob...)
+	c.Cost = append([]int(nil), m.Cost...)
+	c.Tax = append([]int(nil), m.Tax...)
+	if m.Surface != nil {
+		c.Surface = append([]int(nil), m.Surface...)
+	}
+	c.Oil = copyZones(m.Oil)
+	if m.Gas != nil {
+		c.Gas = copyZones(m.Gas)
+	}
+	return c
+}
+
+func copyZones(layer [][]int) [][]int {
+	c := make([][]int, len(layer))
+	for s, zones := range layer {
+		c[s] = append([]int(nil), zones...)
+	}
+	return c
+}
+
+// toMap exports the field as a map with the given name.
+func (f *field) toMap(name string) Map {
+	m := Map{
+		Name:     name,
+		Height:   f.height,
+		Width:    f.width,
+		Topology: f.topo,
+		Prob:     f.prob,
+		Cost:     f.cost,
+		Tax:      f.tax,
+		Oil:      f.oil,
+		Gas:      f.gas,
+	}
+	if f.surface != nil {
+		m.Surface = make([]int, len(f.surface))
+		for s, feature := range f.surface {
+			m.Surface[s] = int(feature)
+		}
+	}
+	return m.Copy()
+}
+
+// fieldFromMap builds a field from a valid map.
+func fieldFromMap(m Map) *field {
+	m = m.Copy()
+	f := &field{
+		height: m.Height,
+		width:  m.Width,
+		topo:   m.Topology,
+		prob:   m.Prob,
+		cost:   m.Cost,
+		tax:    m.Tax,
+		oil:    m.Oil,
+		gas:    m.Gas,
+	}
+	if m.Surface != nil {
+		f.surface = make([]Feature, len(m.Surface))
+		for s, feature := range m.Surface {
+			f.surface[s] = Feature(feature)
+		}
+	}
+	f.routeFreight()
+	f.labelReservoirs()
+	return f
+}
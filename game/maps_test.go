@@ -0,0 +1,136 @@
+package game
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/png"
+	"reflect"
+	"testing"
+)
+
+func testMap() Map {
+	return Map{
+		Name:   "corsicana",
+		Height: 2,
+		Width:  3,
+		Prob:   []int{1, 50, 100, 20, 30, 40},
+		Cost:   []int{10, 20, 30, 250, 200, 100},
+		Tax:    []int{100, 550, 300, 400, 101, 549},
+		Oil:    [][]int{nil, {2}, {2, 5}, {9}, nil, {1, 3, 7}},
+	}
+}
+
+func TestMapRoundTrip(t *testing.T) {
+	m := testMap()
+	if err := m.Validate(); err != nil {
+		t.Fatalf("Validate() -> %s", err)
+	}
+
+	g := New(WithMap(m)).(*game)
+	if g.f.height != 2 || g.f.width != 3 {
+		t.Fatalf("game field is %dx%d; expect 2x3", g.f.height, g.f.width)
+	}
+	if res := g.f.reservoir(1, 2); len(res) != 2 {
+		t.Errorf("reservoir(1, 2) -> %v; expect sites 1 and 2", res)
+	}
+
+	exported := g.Map()
+	exported.Name = m.Name
+	if !reflect.DeepEqual(exported, m) {
+		t.Errorf("Map() -> %+v; expect %+v", exported, m)
+	}
+}
+
+func TestMapValidate(t *testing.T) {
+	var tests = []func(*Map){
+		func(m *Map) { m.Height = 0 },
+		func(m *Map) { m.Prob = m.Prob[1:] },
+		func(m *Map) { m.Cost[0] = maxCost + 1 },
+		func(m *Map) { m.Tax[5] = minTax - 1 },
+		func(m *Map) { m.Oil[2] = []int{5, 2} },
+		func(m *Map) { m.Oil[3] = []int{10} },
+		func(m *Map) { m.Oil = m.Oil[:5] },
+		func(m *Map) { m.Height, m.Width, m.Topology = 3, 2, Topology{Hex, true} },
+		func(m *Map) { m.Gas = make([][]int, 6); m.Gas[1] = []int{2} },
+	}
+	for i, breakMap := range tests {
+		m := testMap()
+		breakMap(&m)
+		if err := m.Validate(); err == nil {
+			t.Errorf("invalid map %d: Validate() -> nil", i)
+		}
+	}
+}
+
+func TestMapApply(t *testing.T) {
+	m := testMap()
+
+	edits := []Edit{
+		{"prob", Region{0, 1, 2, 2}, 75},
+		{"oil", Region{1, 0, 1, 3}, 4},
+		{"oil", Region{0, 2, 1, 1}, 0},
+	}
+	for _, e := range edits {
+		if err := m.Apply(e); err != nil {
+			t.Fatalf("Apply(%+v) -> %s", e, err)
+		}
+	}
+
+	if expect := []int{1, 75, 75, 20, 75, 75}; !reflect.DeepEqual(m.Prob, expect) {
+		t.Errorf("prob after edits %v; expect %v", m.Prob, expect)
+	}
+	if expect := [][]int{nil, {2}, nil, {4}, {4}, {4}}; !reflect.DeepEqual(m.Oil, expect) {
+		t.Errorf("oil after edits %v; expect %v", m.Oil, expect)
+	}
+
+	bad := []Edit{
+		{"depth", Region{0, 0, 1, 1}, 1},
+		{"prob", Region{1, 2, 2, 1}, 50},
+		{"prob", Region{0, 0, 0, 1}, 50},
+		{"cost", Region{0, 0, 1, 1}, 5},
+		{"tax", Region{-1, 0, 1, 1}, 200},
+	}
+	for _, e := range bad {
+		if err := m.Apply(e); err == nil {
+			t.Errorf("Apply(%+v) -> nil; expect error", e)
+		}
+	}
+}
+
+func TestMapLayerPNG(t *testing.T) {
+	m := testMap()
+
+	decoded := Map{Name: m.Name, Height: m.Height, Width: m.Width}
+	for _, layer := range Layers {
+		var buf bytes.Buffer
+		if err := m.EncodeLayer(&buf, layer); err != nil {
+			t.Fatalf("EncodeLayer(%s) -> %s", layer, err)
+		}
+		if err := decoded.DecodeLayer(&buf, layer); err != nil {
+			t.Fatalf("DecodeLayer(%s) -> %s", layer, err)
+		}
+	}
+	if !reflect.DeepEqual(decoded, m) {
+		t.Errorf("decoded PNG layers %+v; expect %+v", decoded, m)
+	}
+
+	// layers must match the map's size
+	var buf bytes.Buffer
+	m.EncodeLayer(&buf, "prob")
+	small := Map{Height: 1, Width: 3}
+	if err := small.DecodeLayer(&buf, "prob"); err == nil {
+		t.Errorf("DecodeLayer of a 2x3 layer into a 1x3 map -> nil; expect error")
+	}
+
+	// pay zones must lie at depths the game can drill to
+	for _, bits := range []uint16{1 << 0, 1<<2 | 1<<(maxOil+1), 1 << 15} {
+		img := image.NewGray16(image.Rect(0, 0, m.Width, m.Height))
+		img.SetGray16(1, 0, color.Gray16{bits})
+		buf.Reset()
+		png.Encode(&buf, img)
+		if err := m.DecodeLayer(&buf, "oil"); err == nil {
+			t.Errorf("DecodeLayer of oil bits %#x -> nil; expect error", bits)
+		}
+	}
+}
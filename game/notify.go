@@ -0,0 +1,130 @@
+package game
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"syscall"
+	"time"
+)
+
+// Event names the reason for a Notification.
+type Event string
+
+const (
+	// TurnOpened is sent to every player when a new week begins.
+	TurnOpened Event = "turn"
+	// DeadlineNear is sent to players who haven't finished their week
+	// shortly before its deadline.
+	DeadlineNear Event = "deadline"
+)
+
+// Notification is a message to one player about their game.
+type Notification struct {
+	Event    Event     `json:"event"`
+	Week     int       `json:"week"`
+	Player   int       `json:"player"`
+	Name     string    `json:"name"`
+	Deadline time.Time `json:"deadline"`
+}
+
+// A Notifier delivers notifications to players who may not be online. Notify
+// gives up when ctx is cancelled, as it is when the game closes.
+type Notifier interface {
+	Notify(ctx context.Context, n Notification) error
+}
+
+type nopNotifier struct{}
+
+func (nopNotifier) Notify(context.Context, Notification) error { return nil }
+
+// webhookTimeout is how long a webhook has to answer a notification.
+const webhookTimeout = 10 * time.Second
+
+// webhookClient only reaches public addresses, so the game's webhook can't be
+// pointed at the server's own network. The addresses are checked as they're
+// dialed, after any redirects and DNS lookups.
+var webhookClient = &http.Client{
+	Timeout: webhookTimeout,
+	Transport: &http.Transport{
+		DialContext: (&net.Dialer{
+			Timeout: webhookTimeout,
+			Control: func(network, address string, _ syscall.RawConn) error {
+				host, _, err := net.SplitHostPort(address)
+				if err != nil {
+					return err
+				}
+				if ip := net.ParseIP(host); ip == nil || !public(ip) {
+					return fmt.Errorf("webhook address %s is not public", host)
+				}
+				return nil
+			},
+		}).DialContext,
+		TLSHandshakeTimeout: webhookTimeout,
+	},
+}
+
+// public reports whether an IP address is out on the internet.
+func public(ip net.IP) bool {
+	return !(ip.IsLoopback() || ip.IsPrivate() || ip.IsUnspecified() ||
+		ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() || ip.IsInterfaceLocalMulticast() || ip.IsMulticast())
+}
+
+// Webhook is a Notifier that POSTs each notification as JSON to a URL. Client
+// defaults to one that times out and only reaches public addresses.
+type Webhook struct {
+	URL    string
+	Client *http.Client
+}
+
+// ParseWebhook checks that a player-supplied URL is fit to be a webhook: http
+// or https, and not addressed to a private network.
+func ParseWebhook(rawurl string) (*Webhook, error) {
+	u, err := url.Parse(rawurl)
+	if err != nil {
+		return nil, err
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return nil, fmt.Errorf("webhook %q is not http or https", rawurl)
+	}
+	host := u.Hostname()
+	if host == "" || host == "localhost" {
+		return nil, fmt.Errorf("webhook %q has no public host", rawurl)
+	}
+	if ip := net.ParseIP(host); ip != nil && !public(ip) {
+		return nil, fmt.Errorf("webhook address %s is not public", host)
+	}
+	return &Webhook{URL: u.String()}, nil
+}
+
+func (w *Webhook) Notify(ctx context.Context, n Notification) error {
+	js, err := json.Marshal(n)
+	if err != nil {
+		return err
+	}
+
+	client := w.Client
+	if client == nil {
+		client = webhookClient
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", w.URL, bytes.NewReader(js))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("webhook %s: %s", w.URL, resp.Status)
+	}
+	return nil
+}
@@ -0,0 +1,87 @@
+package game
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestWebhook(t *testing.T) {
+	received := make(chan Notification, 1)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "POST" {
+			t.Errorf("webhook method: expect POST; got %s", r.Method)
+		}
+		var n Notification
+		if err := json.NewDecoder(r.Body).Decode(&n); err != nil {
+			t.Errorf("webhook body: %s", err)
+		}
+		received <- n
+	}))
+	defer ts.Close()
+
+	expect := Notification{
+		Event:    TurnOpened,
+		Week:     3,
+		Player:   2,
+		Name:     "bob",
+		Deadline: time.Date(1931, 10, 5, 12, 0, 0, 0, time.UTC),
+	}
+
+	hook := &Webhook{URL: ts.URL, Client: ts.Client()}
+	if err := hook.Notify(context.Background(), expect); err != nil {
+		t.Fatalf("Notify() -> %s", err)
+	}
+	if n := <-received; !reflect.DeepEqual(n, expect) {
+		t.Errorf("webhook received %+v; expect %+v", n, expect)
+	}
+}
+
+func TestWebhookError(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "gone fishing", http.StatusServiceUnavailable)
+	}))
+	defer ts.Close()
+
+	hook := &Webhook{URL: ts.URL, Client: ts.Client()}
+	if err := hook.Notify(context.Background(), Notification{Event: DeadlineNear}); err == nil {
+		t.Errorf("Notify() to failing webhook -> nil; expect error")
+	}
+}
+
+func TestParseWebhook(t *testing.T) {
+	if _, err := ParseWebhook("https://example.com/hook"); err != nil {
+		t.Errorf("ParseWebhook() of a public URL -> %s", err)
+	}
+	for _, rawurl := range []string{
+		"ftp://example.com/hook",
+		"http:///hook",
+		"http://localhost:8888/game/",
+		"http://127.0.0.1/",
+		"http://10.0.0.1/",
+		"http://169.254.169.254/latest/meta-data/",
+		"http://[::1]:8888/",
+	} {
+		if _, err := ParseWebhook(rawurl); err == nil {
+			t.Errorf("ParseWebhook(%q) -> nil; expect error", rawurl)
+		}
+	}
+}
+
+func TestWebhookPrivate(t *testing.T) {
+	reached := false
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		reached = true
+	}))
+	defer ts.Close()
+
+	// a public name may still resolve to a private address
+	hook := &Webhook{URL: ts.URL}
+	if err := hook.Notify(context.Background(), Notification{Event: TurnOpened}); err == nil || reached {
+		t.Errorf("Notify() to a loopback webhook -> %v; expect error", err)
+	}
+}
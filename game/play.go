@@ -11,62 +11,180 @@ const (
 // a playFn represent's the players gameplay state within a week.
 // calling the function transitions the state based on incoming
 // player moves. it returns the playFn for the next state transition.
+// playFns run with the game locked.
 type playFn func(*game, entity) playFn
 
-func survey(g *game, playerID entity) playFn {
-	log.Printf("player %d survey state", playerID)
-	var move site
-
-Loop:
+// next shows the player their view until they make a move, and returns it.
+// the game is unlocked while it waits, and the view is rendered afresh each
+// time it's asked for. ok is false when the week expires or the game closes
+// first.
+func (g *game) next(playerID entity, view func() View) (move site, ok bool) {
 	for {
+		v := view()
+		g.mu.Unlock()
 		select {
-		case g.view[playerID] <- surveyView(g, playerID):
+		case g.view[playerID] <- v:
 		case move = <-g.move[playerID]:
-			if !g.world.IsSurveyor(playerID) {
-				log.Printf("player %d cannot survey", playerID)
-				break
-			}
+			ok = true
+		case <-g.expired:
+			g.mu.Lock()
+			return 0, false
+		case <-g.done:
+			g.mu.Lock()
+			return 0, false
+		}
+		g.mu.Lock()
+		if ok {
+			return move, true
+		}
+	}
+}
 
-			if _, ok := g.deeds[move]; ok {
-				log.Printf("site %d already surveyed; ignoring player %d", move, playerID)
-				break
+func survey(g *game, playerID entity) playFn {
+	log.Printf("player %d survey state", playerID)
+	for {
+		move, ok := g.next(playerID, func() View { return surveyView(g, playerID) })
+		if !ok {
+			return nil
+		}
+
+		// real-time players may check on their wells between surveys
+		if move == done && g.mode == Realtime {
+			return wells
+		}
+
+		if !g.world.IsSurveyor(playerID) {
+			log.Printf("player %d cannot survey", playerID)
+			continue
+		}
+		if move < 0 || int(move) >= len(g.f.cost) {
+			log.Printf("site %d is off the field; ignoring player %d", move, playerID)
+			continue
+		}
+
+		if e, ok := g.world.DeedAt(move); ok {
+			// a player's own wells may be re-entered or worked over instead
+			if g.reenterable(playerID, e) {
+				return reenter(move)
 			}
-			break Loop
+			if g.world.Owner(e).player == playerID && g.producing(e) {
+				return workover(move)
+			}
+			log.Printf("site %d already surveyed; ignoring player %d", move, playerID)
+			continue
+		}
+		if !g.f.drillable(move) {
+			log.Printf("site %d is in town; ignoring player %d", move, playerID)
+			continue
 		}
-	}
 
-	log.Printf("player %d surveying site %d", playerID, move)
-	g.deeds[move] = &deed{player: playerID, week: g.week}
-	g.world.ClearSurveyor(playerID)
+		log.Printf("player %d surveying site %d", playerID, move)
+		g.record(Surveyed{playerID, move, g.world.NewEntity()})
+		g.world.ClearSurveyor(playerID)
+		return report(move)
+	}
+}
 
-	return report(move)
+// summary is the playFn for the start of a week, showing how everyone fared
+// at the turn of the week. any move moves on to the next state; a sequential
+// player's turn waits until they've read it.
+func summary(next playFn) playFn {
+	return func(g *game, playerID entity) playFn {
+		log.Printf("player %d summary state", playerID)
+		if _, ok := g.next(playerID, func() View { return summaryView(g) }); !ok {
+			return nil
+		}
+		return next
+	}
 }
 
-func next(index []entity, e entity) entity {
-	i, _ := linfind(index, e)
-	if i == len(index)-1 {
-		return index[0]
+// wait is the playFn for a player waiting on their turn in a sequential game.
+func wait(g *game, playerID entity) playFn {
+	log.Printf("player %d wait state", playerID)
+	for {
+		v := waitView(g, playerID)
+		g.mu.Unlock()
+		select {
+		case g.view[playerID] <- v:
+			g.mu.Lock()
+		case move := <-g.move[playerID]:
+			g.mu.Lock()
+			log.Printf("ignoring move %d from player %d; waiting for player %d", move, playerID, g.turn)
+		case <-g.wake[playerID]:
+			g.mu.Lock()
+			return survey
+		case <-g.expired:
+			g.mu.Lock()
+			return nil
+		case <-g.done:
+			g.mu.Lock()
+			return nil
+		}
 	}
-	return index[i+1]
 }
 
 func report(siteID site) playFn {
 	// return this player's function for surveyor's report at specific site
 	return func(g *game, playerID entity) playFn {
 		log.Printf("player %d report state @ site %d", playerID, siteID)
-		var move site
 		for {
-			select {
-			case g.view[playerID] <- reportView(g, playerID, siteID):
-			case move = <-g.move[playerID]:
-				if move == no {
-					return wells
-				}
-				if move == yes {
-					return drill(siteID)
-				}
-				log.Printf("ignoring invalid report move from player %d move %d", playerID, move)
+			move, ok := g.next(playerID, func() View { return reportView(g, playerID, siteID) })
+			if !ok {
+				return nil
+			}
+			if move == no {
+				return wells
+			}
+			if move == yes {
+				return drill(siteID)
 			}
+			log.Printf("ignoring invalid report move from player %d move %d", playerID, move)
+		}
+	}
+}
+
+func reenter(siteID site) playFn {
+	// return this player's function for deciding whether to re-enter a well
+	return func(g *game, playerID entity) playFn {
+		log.Printf("player %d reenter state @ site %d", playerID, siteID)
+		for {
+			move, ok := g.next(playerID, func() View { return reenterView(g, playerID, siteID) })
+			if !ok {
+				return nil
+			}
+			if move == no {
+				return survey
+			}
+			if move == yes {
+				log.Printf("player %d re-entering site %d at bit %d", playerID, siteID, g.world.DrillState(g.deed(siteID)).bit)
+				g.record(Reentered{playerID, siteID, g.remobilizeFee(siteID)})
+				g.world.ClearSurveyor(playerID)
+				return drill(siteID)
+			}
+			log.Printf("ignoring invalid reenter move from player %d move %d", playerID, move)
+		}
+	}
+}
+
+func workover(siteID site) playFn {
+	// return this player's function for deciding whether to work over a well
+	return func(g *game, playerID entity) playFn {
+		log.Printf("player %d workover state @ site %d", playerID, siteID)
+		for {
+			move, ok := g.next(playerID, func() View { return workoverView(g, playerID, siteID) })
+			if !ok {
+				return nil
+			}
+			if move == no {
+				return survey
+			}
+			if move == yes {
+				log.Printf("player %d working over site %d", playerID, siteID)
+				g.record(WorkedOver{playerID, siteID, g.workoverFee(siteID)})
+				g.world.ClearSurveyor(playerID)
+				return wells
+			}
+			log.Printf("ignoring invalid workover move from player %d move %d", playerID, move)
 		}
 	}
 }
@@ -77,60 +195,127 @@ func drill(siteID site) playFn {
 	// return this player's function for drilling a specific site
 	return func(g *game, playerID entity) playFn {
 		log.Printf("player %d drill state @ site %d", playerID, siteID)
-		oil := g.f.oil[siteID]
-		deed := g.deeds[siteID]
+		drilling := g.world.DrillState(g.deed(siteID))
 
-	Loop:
 		for {
-			select {
-			case g.view[playerID] <- view(g, playerID):
-			case move := <-g.move[playerID]:
-				if move == done {
-					log.Printf("player %d done drilling site %d", playerID, siteID)
-					break Loop
-				}
+			move, ok := g.next(playerID, func() View { return view(g, playerID) })
+			if !ok {
+				return nil
+			}
+			if move == done {
+				log.Printf("player %d done drilling site %d", playerID, siteID)
+				return wells
+			}
 
-				log.Printf("player %d drilling site %d with bit %d", playerID, siteID, deed.bit)
-				deed.bit++
-				deed.pnl -= g.f.cost[siteID]
+			if !g.canDrill(playerID) {
+				log.Printf("player %d reached drill limit for week %d", playerID, g.week)
+				continue
+			}
 
-				if deed.bit == oil || deed.bit == 9 {
-					log.Printf("player %d done drilling site %d", playerID, siteID)
-					break Loop
+			log.Printf("player %d drilling site %d with bit %d", playerID, siteID, drilling.bit)
+			g.record(Drilled{playerID, siteID, drilling.bit + 1, g.f.cost[siteID]})
+
+			if g.f.zone(siteID, drilling.bit) {
+				log.Printf("player %d struck oil at site %d with bit %d", playerID, siteID, drilling.bit)
+				return complete(siteID)
+			}
+			if g.f.gasZone(siteID, drilling.bit) {
+				log.Printf("player %d struck gas at site %d with bit %d", playerID, siteID, drilling.bit)
+				return complete(siteID)
+			}
+			if drilling.bit == maxOil {
+				log.Printf("player %d done drilling site %d", playerID, siteID)
+				return wells
+			}
+		}
+	}
+}
+
+func complete(siteID site) playFn {
+	// return this player's function for deciding whether to complete a well
+	// in the pay zone the bit just struck or to keep drilling for a deeper one
+	return func(g *game, playerID entity) playFn {
+		log.Printf("player %d complete state @ site %d", playerID, siteID)
+		drilling := g.world.DrillState(g.deed(siteID))
+		for {
+			move, ok := g.next(playerID, func() View { return completeView(g, playerID, siteID) })
+			if !ok {
+				return nil
+			}
+			if move == yes {
+				log.Printf("player %d completing site %d at bit %d", playerID, siteID, drilling.bit)
+				g.record(Completed{playerID, siteID, drilling.bit, !g.f.zone(siteID, drilling.bit)})
+				if _, ok := g.f.pipelineAccess(siteID); ok {
+					return connect(siteID)
 				}
+				return wells
 			}
+			if move == no {
+				if drilling.bit == maxOil {
+					log.Printf("player %d abandoning site %d at total depth", playerID, siteID)
+					return wells
+				}
+				return drill(siteID)
+			}
+			log.Printf("ignoring invalid complete move from player %d move %d", playerID, move)
+		}
+	}
+}
+
+func connect(siteID site) playFn {
+	// return this player's function for deciding whether to connect a new
+	// well to the pipeline and sell its gas, or flare it
+	return func(g *game, playerID entity) playFn {
+		log.Printf("player %d connect state @ site %d", playerID, siteID)
+		for {
+			move, ok := g.next(playerID, func() View { return connectView(g, playerID, siteID) })
+			if !ok {
+				return nil
+			}
+			if move == yes {
+				fee, _ := g.f.pipelineAccess(siteID)
+				log.Printf("player %d connecting site %d to the pipeline", playerID, siteID)
+				g.record(Connected{playerID, siteID, fee})
+				return wells
+			}
+			if move == no {
+				log.Printf("player %d flaring gas at site %d", playerID, siteID)
+				return wells
+			}
+			log.Printf("ignoring invalid connect move from player %d move %d", playerID, move)
 		}
-		return wells
 	}
 }
 
 func wells(g *game, playerID entity) playFn {
 	log.Printf("player %d wells state", playerID)
-Loop:
 	for {
-		select {
-		case g.view[playerID] <- wellsView(g, playerID):
-		case move := <-g.move[playerID]:
+		move, ok := g.next(playerID, func() View { return wellsView(g, playerID) })
+		if !ok {
+			return nil
+		}
 
-			if move == done {
-				log.Printf("player %d done selling", playerID)
-				break Loop
-			}
+		if move == done {
+			log.Printf("player %d done selling", playerID)
+			break
+		}
 
-			deed := g.deeds[move]
-			if deed == nil || deed.player != playerID {
-				log.Printf("ignoring sale for site %d; player %d does not own deed", move, playerID)
-				break
-			}
-			if deed.stop > 0 {
-				log.Printf("ignoring sale for site %d; already sold in week %d", move, deed.stop)
-				break
-			}
-			log.Printf("player %d selling site %d", playerID, move)
-			g.deeds[move].stop = g.week
+		e, ok := g.world.DeedAt(move)
+		if !ok || g.world.Owner(e).player != playerID {
+			log.Printf("ignoring sale for site %d; player %d does not own deed", move, playerID)
+			continue
 		}
+		if stop := g.world.Sold(e); stop > 0 {
+			log.Printf("ignoring sale for site %d; already sold in week %d", move, stop)
+			continue
+		}
+		log.Printf("player %d selling site %d", playerID, move)
+		g.record(Sold{playerID, move})
 	}
 
-	g.view[playerID] <- lobbyView(g)
+	// real-time weeks roll on without the lobby
+	if g.mode == Realtime {
+		return survey
+	}
 	return nil
 }
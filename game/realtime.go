@@ -0,0 +1,50 @@
+package game
+
+import (
+	"log"
+	"time"
+)
+
+const daysPerWeek = 7
+
+// tick runs the wall clock for a real-time game. Each day it accrues a share
+// of every producing well's weekly earnings, and after the last day of the
+// week it starts the next one. It returns when the game is closed.
+func (g *game) tick() {
+	day := time.NewTicker(g.weekLength / daysPerWeek)
+	defer day.Stop()
+
+	for {
+		g.unlocked(func() {
+			select {
+			case <-day.C:
+			case <-g.done:
+			}
+		})
+		if g.closed() {
+			return
+		}
+		g.day++
+		g.accrue(g.day)
+		if g.day == daysPerWeek {
+			g.day = 0
+			g.nextWeek()
+			log.Printf("real-time clock started week %d", g.week)
+		}
+	}
+}
+
+// accrue credits each producing well with its earnings for the given day of
+// the week.
+func (g *game) accrue(day int) {
+	g.world.EachWell(func(e entity, p *production) {
+		if g.producing(e) {
+			g.record(g.earnings(e).share(day))
+		}
+	})
+}
+
+// canDrill reports whether the player has bits left to drill this week.
+func (g *game) canDrill(playerID entity) bool {
+	return g.mode != Realtime || g.drilled[playerID] < g.drillLimit
+}
@@ -0,0 +1,230 @@
+package game
+
+import (
+	"bufio"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"io"
+	"strconv"
+)
+
+// the same color scales as the client
+var (
+	diverging = []string{"#4575b4", "#91bfdb", "#e0f3f8", "#fee090", "#fc8d59", "#d73027"}
+	oilColors = []string{"#4d4d4d", "#878787", "#bababa", "#e0e0e0", "#ffffff", "#fddbc7", "#f4a582", "#d6604d", "#b2182b"}
+	// open, river, road, pipeline, town
+	surfaceColors = []string{"#1a1a1a", "#4575b4", "#bababa", "#fc8d59", "#d73027"}
+	noOil         = "#000000"
+	// sites whose pay zones players haven't found yet
+	unknownColor = "#f0f0f0"
+
+	// well markers: producing, dry or stopped short, and sold
+	producingColor = "#1a9850"
+	dryColor       = "#000000"
+	soldColor      = "#808080"
+)
+
+// MaxScale is the widest a site may be rendered, in pixels.
+const MaxScale = 16
+
+// RenderOptions chooses what to render and how.
+type RenderOptions struct {
+	// Layer is one of the map's Layers. The oil and gas layers show each
+	// site's shallowest pay zone.
+	Layer string
+	// Format is "png" or "svg".
+	Format string
+	// Scale is the width of a site in pixels; sites are half again as
+	// tall. Zero is the client's 12 pixels, and no more than MaxScale.
+	Scale int
+	// Wells overlays a game's wells on the layer.
+	Wells bool
+	// Revealed limits a game's oil and gas layers to the pay zones its
+	// players have drilled down to, or the sites they've drilled dry.
+	Revealed bool
+}
+
+// a marker overlays a well on a rendered layer.
+type marker struct {
+	site  site
+	color string
+}
+
+// RenderMap draws one layer of a map as a PNG or SVG image.
+func RenderMap(w io.Writer, m Map, opts RenderOptions) error {
+	return render(w, m, nil, nil, opts)
+}
+
+// Render draws one layer of the game's field, optionally with its wells.
+func (g *game) Render(w io.Writer, opts RenderOptions) error {
+	m := g.f.toMap("")
+	var markers []marker
+	var hidden []bool
+	g.mu.Lock()
+	if opts.Revealed && (opts.Layer == "oil" || opts.Layer == "gas") {
+		hidden = g.hidden(m, opts.Layer)
+	}
+	if opts.Wells {
+		for _, e := range g.world.Deeds() {
+			if g.world.DrillState(e).bit == 0 {
+				// surveyed but never drilled
+				continue
+			}
+			c := dryColor
+			switch {
+			case g.world.Sold(e) > 0:
+				c = soldColor
+			case g.world.Production(e) != nil:
+				c = producingColor
+			}
+			markers = append(markers, marker{g.world.Location(e), c})
+		}
+	}
+	g.mu.Unlock()
+	return render(w, m, markers, hidden, opts)
+}
+
+// hidden returns which sites' shallowest pay zones in the oil or gas layer no
+// player has found: a zone is found once a bit reaches it, and a site with
+// none once it's drilled to total depth.
+func (g *game) hidden(m Map, layer string) []bool {
+	zones := *m.zones(layer)
+	hidden := make([]bool, len(zones))
+	for s := range hidden {
+		hidden[s] = true
+	}
+	for _, e := range g.world.Deeds() {
+		s := g.world.Location(e)
+		bit := g.world.DrillState(e).bit
+		if len(zones[s]) > 0 {
+			hidden[s] = bit < zones[s][0]
+		} else {
+			hidden[s] = bit < maxOil
+		}
+	}
+	return hidden
+}
+
+// quantize maps a value in [min, max] onto one of the colors, like d3's
+// quantize scale.
+func quantize(v, min, max int, colors []string) string {
+	i := (v - min) * len(colors) / (max - min)
+	if i < 0 {
+		i = 0
+	}
+	if i >= len(colors) {
+		i = len(colors) - 1
+	}
+	return colors[i]
+}
+
+// colors returns the color of every site in one of the map's layers.
+func colors(m Map, layer string) ([]string, error) {
+	min, max, err := bounds(layer)
+	if err != nil {
+		return nil, err
+	}
+
+	n := m.Height * m.Width
+	cs := make([]string, n)
+	switch layer {
+	case "oil", "gas":
+		for s, zones := range *m.zones(layer) {
+			cs[s] = noOil
+			if len(zones) > 0 {
+				cs[s] = quantize(zones[0], min, max, oilColors)
+			}
+		}
+	case "surface":
+		for s := range cs {
+			cs[s] = surfaceColors[Open]
+			if m.Surface != nil {
+				cs[s] = surfaceColors[m.Surface[s]]
+			}
+		}
+	default:
+		values, _ := m.values(layer)
+		for s, v := range values {
+			cs[s] = quantize(v, min, max, diverging)
+		}
+	}
+	return cs, nil
+}
+
+func render(w io.Writer, m Map, markers []marker, hidden []bool, opts RenderOptions) error {
+	cs, err := colors(m, opts.Layer)
+	if err != nil {
+		return err
+	}
+	for s, h := range hidden {
+		if h {
+			cs[s] = unknownColor
+		}
+	}
+	scale := opts.Scale
+	if scale > MaxScale {
+		return fmt.Errorf("scale %d is over %d", scale, MaxScale)
+	}
+	if scale < 1 {
+		scale = 12
+	}
+	cw, ch := scale, scale*3/2
+	if ch < 1 {
+		ch = 1
+	}
+
+	// hex grids shift odd rows right by half a site
+	origin := func(s site) (int, int) {
+		y, x := int(s)/m.Width, int(s)%m.Width
+		px := x * cw
+		if m.Topology.Grid == Hex && y%2 == 1 {
+			px += cw / 2
+		}
+		return px, y * ch
+	}
+	width, height := m.Width*cw, m.Height*ch
+	if m.Topology.Grid == Hex {
+		width += cw / 2
+	}
+
+	switch opts.Format {
+	case "png":
+		img := image.NewRGBA(image.Rect(0, 0, width, height))
+		for s, c := range cs {
+			x, y := origin(site(s))
+			draw.Draw(img, image.Rect(x, y, x+cw, y+ch), image.NewUniform(hexColor(c)), image.Point{}, draw.Src)
+		}
+		for _, mk := range markers {
+			// a square in the middle third of the site
+			x, y := origin(mk.site)
+			r := image.Rect(x+cw/3, y+ch/3, x+cw-cw/3, y+ch-ch/3)
+			draw.Draw(img, r, image.NewUniform(hexColor(mk.color)), image.Point{}, draw.Src)
+		}
+		return png.Encode(w, img)
+
+	case "svg":
+		bw := bufio.NewWriter(w)
+		fmt.Fprintf(bw, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" shape-rendering="crispEdges">`+"\n", width, height)
+		for s, c := range cs {
+			x, y := origin(site(s))
+			fmt.Fprintf(bw, `<rect x="%d" y="%d" width="%d" height="%d" fill="%s"/>`+"\n", x, y, cw, ch, c)
+		}
+		for _, mk := range markers {
+			x, y := origin(mk.site)
+			r := float64(cw) / 3
+			fmt.Fprintf(bw, `<circle cx="%g" cy="%g" r="%g" fill="%s" stroke="#ffffff"/>`+"\n", float64(x)+float64(cw)/2, float64(y)+float64(ch)/2, r, mk.color)
+		}
+		fmt.Fprintln(bw, "</svg>")
+		return bw.Flush()
+	}
+	return fmt.Errorf("unknown image format %q", opts.Format)
+}
+
+// hexColor parses a color in the form #rrggbb.
+func hexColor(s string) color.RGBA {
+	v, _ := strconv.ParseUint(s[1:], 16, 32)
+	return color.RGBA{uint8(v >> 16), uint8(v >> 8), uint8(v), 0xff}
+}
@@ -0,0 +1,117 @@
+package game
+
+import (
+	"bytes"
+	"image/png"
+	"strings"
+	"testing"
+)
+
+func TestQuantize(t *testing.T) {
+	tests := []struct {
+		v      int
+		expect string
+	}{
+		{minProb, diverging[0]},
+		{17, diverging[0]},
+		{18, diverging[1]},
+		{50, diverging[2]},
+		{maxProb, diverging[5]},
+	}
+	for _, test := range tests {
+		if c := quantize(test.v, minProb, maxProb, diverging); c != test.expect {
+			t.Errorf("quantize(%d) -> %s; expect %s", test.v, c, test.expect)
+		}
+	}
+}
+
+func TestRenderPNG(t *testing.T) {
+	m := testMap()
+	var buf bytes.Buffer
+	if err := RenderMap(&buf, m, RenderOptions{Layer: "prob", Format: "png", Scale: 4}); err != nil {
+		t.Fatalf("RenderMap() -> %s", err)
+	}
+	img, err := png.Decode(&buf)
+	if err != nil {
+		t.Fatalf("decoding render: %s", err)
+	}
+	if size := img.Bounds().Size(); size.X != 3*4 || size.Y != 2*6 {
+		t.Errorf("render is %dx%d; expect 12x12", size.X, size.Y)
+	}
+
+	// site 2 is the right end of the top row, with prob 100
+	r, g, b, _ := img.At(2*4+1, 1).RGBA()
+	expect := hexColor(diverging[5])
+	if uint8(r>>8) != expect.R || uint8(g>>8) != expect.G || uint8(b>>8) != expect.B {
+		t.Errorf("site 2 color %02x%02x%02x; expect %s", r>>8, g>>8, b>>8, diverging[5])
+	}
+
+	if err := RenderMap(&buf, m, RenderOptions{Layer: "depth", Format: "png"}); err == nil {
+		t.Errorf("rendering unknown layer: expect error")
+	}
+	if err := RenderMap(&buf, m, RenderOptions{Layer: "oil", Format: "gif"}); err == nil {
+		t.Errorf("rendering unknown format: expect error")
+	}
+}
+
+func TestRenderSVG(t *testing.T) {
+	g := New(WithMap(testMap())).(*game)
+	addWell(g, 1, 1, 2)
+	dry := g.world.NewEntity()
+	g.world.AddDeed(dry, 4, 1, 1)
+	g.world.DrillState(dry).bit = 9
+	g.world.AddDeed(g.world.NewEntity(), 5, 1, 1)
+
+	var buf bytes.Buffer
+	if err := g.Render(&buf, RenderOptions{Layer: "prob", Format: "svg", Wells: true}); err != nil {
+		t.Fatalf("Render() -> %s", err)
+	}
+	svg := buf.String()
+	if n := strings.Count(svg, "<rect"); n != 6 {
+		t.Errorf("svg has %d sites; expect 6", n)
+	}
+	// surveyed sites that were never drilled aren't wells
+	if n := strings.Count(svg, "<circle"); n != 2 {
+		t.Errorf("svg has %d wells; expect 2", n)
+	}
+	if !strings.Contains(svg, producingColor) || !strings.Contains(svg, dryColor) {
+		t.Errorf("svg is missing producing or dry wells:\n%s", svg)
+	}
+}
+
+func TestRenderRevealed(t *testing.T) {
+	g := New(WithMap(testMap())).(*game)
+	defer g.Close()
+	// site 1's zone at 2 has been struck, site 4 drilled dry to total depth,
+	// and site 2 stopped short of its zone
+	addWell(g, 1, 1, 2)
+	dry := g.world.NewEntity()
+	g.world.AddDeed(dry, 4, 1, 1)
+	g.world.DrillState(dry).bit = maxOil
+	short := g.world.NewEntity()
+	g.world.AddDeed(short, 2, 1, 1)
+	g.world.DrillState(short).bit = 1
+
+	var buf bytes.Buffer
+	if err := g.Render(&buf, RenderOptions{Layer: "oil", Format: "svg", Revealed: true}); err != nil {
+		t.Fatalf("Render() -> %s", err)
+	}
+	if n := strings.Count(buf.String(), unknownColor); n != 4 {
+		t.Errorf("svg has %d unknown sites; expect 4\n%s", n, buf.String())
+	}
+	if !strings.Contains(buf.String(), noOil) {
+		t.Errorf("svg is missing the dry site:\n%s", buf.String())
+	}
+
+	buf.Reset()
+	if err := g.Render(&buf, RenderOptions{Layer: "oil", Format: "svg"}); err != nil {
+		t.Fatalf("Render() -> %s", err)
+	}
+	if strings.Contains(buf.String(), unknownColor) {
+		t.Errorf("full render hides sites:\n%s", buf.String())
+	}
+
+	if err := g.Render(&buf, RenderOptions{Layer: "prob", Format: "png", Scale: MaxScale + 1}); err == nil {
+		t.Errorf("rendering at scale %d: expect error", MaxScale+1)
+	}
+}
@@ -0,0 +1,189 @@
+package game
+
+import "fmt"
+
+// a replayer steps a copy of a game through its journal a week at a time.
+type replayer struct {
+	g       *game
+	entries []Entry
+	next    int
+}
+
+func newReplayer(entries []Entry) (*replayer, error) {
+	if len(entries) == 0 || entries[0].Kind != "created" {
+		return nil, fmt.Errorf("journal has no seed")
+	}
+	r := &replayer{
+		g: &game{
+			drilled:     make(map[entity]int),
+			produced:    make(map[int]int),
+			producedGas: make(map[int]int),
+		},
+		entries: entries,
+	}
+	return r, nil
+}
+
+// through applies the changes up to the end of a week and returns them.
+func (r *replayer) through(week int) []Entry {
+	start := r.next
+	for r.next < len(r.entries) && r.entries[r.next].Week <= week {
+		r.entries[r.next].Change.apply(r.g)
+		r.next++
+	}
+	return r.entries[start:r.next]
+}
+
+// weeks returns the last week in the journal.
+func (r *replayer) weeks() int {
+	return r.entries[len(r.entries)-1].Week
+}
+
+// a move is a change a player made.
+type move interface {
+	Change
+	by() entity
+}
+
+func (c Surveyed) by() entity   { return c.Player }
+func (c Drilled) by() entity    { return c.Player }
+func (c Reentered) by() entity  { return c.Player }
+func (c WorkedOver) by() entity { return c.Player }
+func (c Completed) by() entity  { return c.Player }
+func (c Connected) by() entity  { return c.Player }
+func (c Sold) by() entity       { return c.Player }
+
+// Timeline is a whole game in one document, for watching it back.
+type Timeline struct {
+	Field Map            `json:"field"`
+	Weeks []TimelineWeek `json:"weeks"`
+}
+
+// TimelineWeek is what happened in one week of a game.
+type TimelineWeek struct {
+	Week      int              `json:"week"`
+	Price     int              `json:"price"`
+	GasPrice  int              `json:"gasPrice"`
+	Incidents []Incident       `json:"incidents"`
+	Players   []TimelinePlayer `json:"players"`
+}
+
+// TimelinePlayer is a player's moves in a week, and their profit and loss at
+// its end.
+type TimelinePlayer struct {
+	Name  string  `json:"name"`
+	Moves []Entry `json:"moves"`
+	PNL   int     `json:"pnl"`
+}
+
+// Timeline replays the game's journal week by week.
+func (g *game) Timeline() (Timeline, error) {
+	r, err := newReplayer(g.journal.week(0))
+	if err != nil {
+		return Timeline{}, err
+	}
+	r.through(0)
+
+	t := Timeline{Field: r.g.f.toMap("")}
+	for week := 1; week <= r.weeks(); week++ {
+		entries := r.through(week)
+
+		moves := make(map[entity][]Entry)
+		for _, e := range entries {
+			if m, ok := e.Change.(move); ok {
+				moves[m.by()] = append(moves[m.by()], e)
+			}
+		}
+
+		tw := TimelineWeek{
+			Week:      week,
+			Price:     r.g.price,
+			GasPrice:  r.g.gasPrice,
+			Incidents: r.g.incidents,
+			Players:   make([]TimelinePlayer, 0),
+		}
+		for _, p := range r.g.world.Players() {
+			tw.Players = append(tw.Players, TimelinePlayer{r.g.world.Name(p), moves[p], r.g.pnl(p)})
+		}
+		t.Weeks = append(t.Weeks, tw)
+	}
+	return t, nil
+}
+
+// Frame is the state of every deed in a game at the end of a week.
+type Frame struct {
+	Week      int           `json:"week"`
+	Weeks     int           `json:"weeks"`
+	Price     int           `json:"price"`
+	GasPrice  int           `json:"gasPrice"`
+	Incidents []Incident    `json:"incidents"`
+	Players   []FramePlayer `json:"players"`
+	Deeds     []FrameDeed   `json:"deeds"`
+}
+
+// FramePlayer is a player's profit and loss at the end of a frame's week.
+type FramePlayer struct {
+	Name string `json:"name"`
+	PNL  int    `json:"pnl"`
+}
+
+// FrameDeed is a deed as it stood at the end of a frame's week.
+type FrameDeed struct {
+	Site      site   `json:"site"`
+	Player    string `json:"player"`
+	Week      int    `json:"week"`
+	Bit       int    `json:"bit"`
+	Zone      int    `json:"zone,omitempty"`
+	Gas       bool   `json:"gas,omitempty"`
+	Connected bool   `json:"connected,omitempty"`
+	Output    int    `json:"output"`
+	GasOutput int    `json:"gasOutput"`
+	Barrels   int    `json:"barrels"`
+	Mcf       int    `json:"mcf"`
+	Sold      int    `json:"sold,omitempty"`
+	PNL       int    `json:"pnl"`
+}
+
+// Frame replays the game's journal to the end of a week.
+func (g *game) Frame(week int) (Frame, error) {
+	r, err := newReplayer(g.journal.week(0))
+	if err != nil {
+		return Frame{}, err
+	}
+	if week < 1 || week > r.weeks() {
+		return Frame{}, fmt.Errorf("journal has no week %d", week)
+	}
+	r.through(week)
+	rg := r.g
+
+	f := Frame{
+		Week:      week,
+		Weeks:     r.weeks(),
+		Price:     rg.price,
+		GasPrice:  rg.gasPrice,
+		Incidents: rg.incidents,
+		Players:   make([]FramePlayer, 0),
+		Deeds:     make([]FrameDeed, 0),
+	}
+	for _, p := range rg.world.Players() {
+		f.Players = append(f.Players, FramePlayer{rg.world.Name(p), rg.pnl(p)})
+	}
+	for _, e := range rg.world.Deeds() {
+		o := rg.world.Owner(e)
+		d := FrameDeed{
+			Site:   rg.world.Location(e),
+			Player: rg.world.Name(o.player),
+			Week:   o.week,
+			Bit:    rg.world.DrillState(e).bit,
+			Sold:   rg.world.Sold(e),
+			PNL:    o.pnl,
+		}
+		if p := rg.world.Production(e); p != nil {
+			d.Zone, d.Gas, d.Connected = p.zone, p.gas, p.connected
+			d.Output, d.GasOutput = p.output, p.gasOutput
+			d.Barrels, d.Mcf = p.produced, p.gasProduced
+		}
+		f.Deeds = append(f.Deeds, d)
+	}
+	return f, nil
+}
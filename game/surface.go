@@ -0,0 +1,221 @@
+package game
+
+import (
+	"math"
+	"math/rand"
+)
+
+// Feature is what lies on the surface of a site.
+type Feature int
+
+const (
+	// Open ground has nothing on it.
+	Open Feature = iota
+	// Rivers must be drilled from barges, at a higher cost.
+	River
+	// Roads carry oil to market by truck.
+	Road
+	// Pipelines carry oil to market for less than trucks.
+	Pipeline
+	// Towns can't be drilled, and wells on their outskirts pay the highest
+	// taxes around. They are served by roads.
+	Town
+
+	minFeature = Open
+	maxFeature = Town
+)
+
+const (
+	// drilling from a barge costs this much more than on dry land
+	riverCost = 1.5
+
+	// shipping a barrel to market by pipeline costs a flat tariff, plus a
+	// gathering charge for each site between the well and the pipeline, in
+	// cents
+	pipelineTariff = 5
+	gatheringRate  = 2
+	// trucking a barrel costs a loading charge, plus a haul for each site
+	// between the well and a road
+	truckLoading = 15
+	truckHaul    = 3
+)
+
+// generateSurface lays out rivers, roads, pipelines and towns over the
+// field, raising the cost of drilling on rivers and the taxes in and around
+// towns.
+func (f *field) generateSurface() {
+	n := f.height * f.width
+	f.surface = make([]Feature, n)
+	scale := math.Sqrt(float64(n) / (24 * 80))
+	count := func(lo, spread int) int {
+		return int(math.Ceil(float64(lo+rand.Intn(spread)) * scale))
+	}
+
+	for i := count(1, 2); i > 0; i-- {
+		from, to := f.crossing()
+		f.paint(f.path(from, to), River)
+	}
+
+	towns := make([]site, count(2, 3))
+	for i := range towns {
+		towns[i] = site(rand.Intn(n))
+	}
+	// roads join the towns together and run off the field to market
+	for i := 1; i < len(towns); i++ {
+		f.paint(f.path(towns[i-1], towns[i]), Road)
+	}
+	from, to := f.crossing()
+	f.paint(f.path(towns[0], from), Road)
+	f.paint(f.path(towns[len(towns)-1], to), Road)
+
+	// a trunk pipeline crosses the field
+	from, to = f.crossing()
+	f.paint(f.path(from, to), Pipeline)
+
+	// towns grow around the pipeline rather than over it
+	for _, t := range towns {
+		for _, s := range append(f.neighbors(t), t) {
+			if f.surface[s] != Pipeline {
+				f.surface[s] = Town
+			}
+		}
+	}
+
+	for s, feature := range f.surface {
+		if feature == River {
+			f.cost[s] = int(math.Min(math.Round(float64(f.cost[s])*riverCost), maxCost))
+		}
+	}
+	f.taxTowns()
+}
+
+// taxTowns raises the taxes in towns, and on the sites around them that can
+// be drilled, to the highest there are.
+func (f *field) taxTowns() {
+	for s, feature := range f.surface {
+		if feature != Town {
+			continue
+		}
+		f.tax[s] = maxTax
+		for _, nbr := range f.neighbors(site(s)) {
+			f.tax[nbr] = maxTax
+		}
+	}
+}
+
+// crossing returns sites on opposite edges of the field, from top to bottom
+// or left to right. a wrapped field's edges meet, so its crossings go
+// halfway around instead.
+func (f *field) crossing() (site, site) {
+	bottom, right := f.height-1, f.width-1
+	if f.topo.Wrap {
+		bottom, right = f.height/2, f.width/2
+	}
+	if rand.Intn(2) == 0 {
+		return site(rand.Intn(f.width)), site(bottom*f.width + rand.Intn(f.width))
+	}
+	return site(rand.Intn(f.height) * f.width), site(rand.Intn(f.height)*f.width + right)
+}
+
+// path returns a meandering route between two sites, stepping each time to
+// a random neighbor that gets closer.
+func (f *field) path(from, to site) []site {
+	route := []site{from}
+	for s := from; s != to; {
+		dist := f.topo.distance(f.height, f.width, int(s), int(to))
+		var closer []site
+		for _, nbr := range f.neighbors(s) {
+			if f.topo.distance(f.height, f.width, int(nbr), int(to)) < dist {
+				closer = append(closer, nbr)
+			}
+		}
+		if len(closer) == 0 {
+			break
+		}
+		s = closer[rand.Intn(len(closer))]
+		route = append(route, s)
+	}
+	return route
+}
+
+// paint lays a feature along a route. Roads and pipelines cross rivers on
+// bridges, so they paint over them.
+func (f *field) paint(route []site, feature Feature) {
+	for _, s := range route {
+		if f.surface[s] < feature {
+			f.surface[s] = feature
+		}
+	}
+}
+
+// routeFreight works out what it costs to ship a barrel from each site to
+// market: by pipeline or truck, whichever is cheaper, plus the cost of
+// getting it there. Fields without a surface ship for free.
+func (f *field) routeFreight() {
+	f.freight = nil
+	f.gathering = nil
+	if f.surface == nil {
+		return
+	}
+
+	pipe := f.steps(func(ft Feature) bool { return ft == Pipeline })
+	f.gathering = pipe
+	road := f.steps(func(ft Feature) bool { return ft == Road || ft == Town })
+	if pipe == nil && road == nil {
+		return
+	}
+
+	f.freight = make([]int, len(f.surface))
+	for s := range f.freight {
+		f.freight[s] = math.MaxInt32
+		if pipe != nil {
+			f.freight[s] = pipelineTariff + gatheringRate*pipe[s]
+		}
+		if road != nil && truckLoading+truckHaul*road[s] < f.freight[s] {
+			f.freight[s] = truckLoading + truckHaul*road[s]
+		}
+	}
+}
+
+// steps returns how many steps each site is from the nearest site with a
+// matching feature, or nil if there are none.
+func (f *field) steps(match func(Feature) bool) []int {
+	steps := make([]int, len(f.surface))
+	var queue []site
+	for s, feature := range f.surface {
+		steps[s] = -1
+		if match(feature) {
+			steps[s] = 0
+			queue = append(queue, site(s))
+		}
+	}
+	if len(queue) == 0 {
+		return nil
+	}
+
+	// search outwards from every matching site at once
+	for i := 0; i < len(queue); i++ {
+		for _, nbr := range f.neighbors(queue[i]) {
+			if steps[nbr] >= 0 {
+				continue
+			}
+			steps[nbr] = steps[queue[i]] + 1
+			queue = append(queue, nbr)
+		}
+	}
+	return steps
+}
+
+// drillable reports whether anyone may drill at the site.
+func (f *field) drillable(s site) bool {
+	return f.surface == nil || f.surface[s] != Town
+}
+
+// transport returns what it costs in cents to ship a barrel from the site
+// to market.
+func (f *field) transport(s site) int {
+	if f.freight == nil {
+		return 0
+	}
+	return f.freight[s]
+}
@@ -0,0 +1,167 @@
+package game
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestGenerateSurface(t *testing.T) {
+	topos := []Topology{{Square4, false}, {Square8, false}, {Hex, true}}
+
+	for _, topo := range topos {
+		f := generateField(Peaks{}, topo, 24, 80)
+
+		counts := make(map[Feature]int)
+		for s, feature := range f.surface {
+			counts[feature]++
+			if feature == Town {
+				for _, nbr := range f.neighbors(site(s)) {
+					if f.drillable(nbr) && f.tax[nbr] != maxTax {
+						t.Errorf("%+v: site %d next to town has tax %d; expect %d", topo, nbr, f.tax[nbr], maxTax)
+					}
+				}
+			}
+			if feature == Pipeline && f.transport(site(s)) != pipelineTariff {
+				t.Errorf("%+v: pipeline at site %d ships for %d; expect %d", topo, s, f.transport(site(s)), pipelineTariff)
+			}
+		}
+		for _, feature := range []Feature{River, Road, Pipeline, Town} {
+			if counts[feature] == 0 {
+				t.Errorf("%+v: no sites with feature %d", topo, feature)
+			}
+		}
+	}
+}
+
+func TestPipelineUnbroken(t *testing.T) {
+	// towns spring up around the trunk pipeline, so it still reaches from
+	// one edge of the field to the other in a single piece
+	for i := 0; i < 20; i++ {
+		f := generateField(Peaks{}, Topology{Square4, false}, 24, 80)
+		var pipeline []site
+		for s, feature := range f.surface {
+			if feature == Pipeline {
+				pipeline = append(pipeline, site(s))
+			}
+		}
+
+		reached := map[site]bool{pipeline[0]: true}
+		for queue := pipeline[:1]; len(queue) > 0; queue = queue[1:] {
+			for _, nbr := range f.neighbors(queue[0]) {
+				if f.surface[nbr] == Pipeline && !reached[nbr] {
+					reached[nbr] = true
+					queue = append(queue, nbr)
+				}
+			}
+		}
+		if len(reached) != len(pipeline) {
+			t.Fatalf("pipeline broken: %d of its %d sites joined up", len(reached), len(pipeline))
+		}
+	}
+}
+
+func TestTownTaxes(t *testing.T) {
+	// a town at the west end of the field, and the same well next to it and
+	// out in the country
+	f := &field{
+		height:  1,
+		width:   5,
+		oil:     [][]int{nil, {2}, nil, {2}, nil},
+		tax:     []int{100, 100, 100, 100, 100},
+		surface: []Feature{Town, Open, Open, Open, Open},
+	}
+	f.taxTowns()
+	g := newTestGame(f)
+	g.price = 100
+	outskirts, country := addWell(g, 1, 1, 2), addWell(g, 3, 2, 2)
+
+	taxSystem(g)
+	if paid, farther := -g.world.Owner(outskirts).pnl, -g.world.Owner(country).pnl; paid <= farther {
+		t.Errorf("well next to town paid %d in tax; expect more than the %d paid farther away", paid, farther)
+	}
+}
+
+func TestWrappedCrossing(t *testing.T) {
+	// on a wrapped field the top and bottom edges meet, so a river from one
+	// to the other would be a single step across the seam
+	f := &field{height: 24, width: 80, topo: Topology{Square4, true}}
+	for i := 0; i < 100; i++ {
+		f.surface = make([]Feature, f.height*f.width)
+		f.paint(f.path(f.crossing()), River)
+		rivers := 0
+		for _, feature := range f.surface {
+			if feature == River {
+				rivers++
+			}
+		}
+		if rivers < f.height/2 {
+			t.Fatalf("river crossing the wrapped field covers %d sites; expect at least %d", rivers, f.height/2)
+		}
+	}
+}
+
+func TestRouteFreight(t *testing.T) {
+	f := &field{
+		height:  1,
+		width:   7,
+		surface: []Feature{Pipeline, Open, River, Open, Open, Open, Road},
+	}
+	f.routeFreight()
+
+	// gathering to the pipeline beats trucking until the last site
+	expect := []int{5, 7, 9, 11, 13, 15, 15}
+	if !reflect.DeepEqual(f.freight, expect) {
+		t.Errorf("freight %v; expect %v", f.freight, expect)
+	}
+
+	// fields built by hand have no surface and ship for free
+	f = &field{height: 1, width: 7}
+	f.routeFreight()
+	if f.transport(3) != 0 {
+		t.Errorf("transport(3) without a surface -> %d; expect 0", f.transport(3))
+	}
+}
+
+func TestSurfaceGame(t *testing.T) {
+	m := Map{
+		Height:  1,
+		Width:   3,
+		Prob:    []int{50, 50, 50},
+		Cost:    []int{10, 10, 10},
+		Tax:     []int{100, 100, 550},
+		Oil:     [][]int{{1}, nil, {1}},
+		Surface: []int{int(Open), int(Pipeline), int(Town)},
+	}
+	g := New(WithMap(m)).(*game)
+
+	p := join(t, g, "bob")
+	makeMove(t, g, p, done)
+
+	if v := makeMove(t, g, p, 2); viewName(t, v) != "survey" {
+		t.Fatalf("surveying in town: expect survey; got %s", viewName(t, v))
+	}
+	if v := makeMove(t, g, p, 0); viewName(t, v) != "report" {
+		t.Fatalf("surveying open ground: expect report; got %s", viewName(t, v))
+	}
+	makeMove(t, g, p, yes)
+	makeMove(t, g, p, 0)
+	if v := makeMove(t, g, p, yes); viewName(t, v) != "connect" {
+		t.Fatalf("completing beside a pipeline: expect connect; got %s", viewName(t, v))
+	}
+	makeMove(t, g, p, no)
+
+	// income is netted of the cost of shipping to the pipeline next door
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	e := g.deed(0)
+	g.world.Production(e).output = 100
+	g.price = 100
+	freight := pipelineTariff + gatheringRate
+	if expect := 100*(100-freight)/100 - 100; g.earnings(e).net() != expect {
+		t.Errorf("earnings -> %d; expect %d", g.earnings(e).net(), expect)
+	}
+
+	if exported := g.Map(); !reflect.DeepEqual(exported.Surface, m.Surface) {
+		t.Errorf("exported surface %v; expect %v", exported.Surface, m.Surface)
+	}
+}
@@ -0,0 +1,177 @@
+package game
+
+import (
+	"log"
+	"math"
+	"math/rand"
+)
+
+// a system applies one of the game's rules at the turn of each week.
+type system func(g *game)
+
+type namedSystem struct {
+	name string
+	run  system
+}
+
+// A System applies a rule of a scenario's own at the turn of each week.
+type System func(t Turn)
+
+// A Turn is a System's handle on the game whose week is turning.
+type Turn struct {
+	g *game
+}
+
+// Week returns the week that's beginning.
+func (t Turn) Week() int {
+	return t.g.week
+}
+
+// Mode returns the game's turn mode.
+func (t Turn) Mode() Mode {
+	return t.g.mode
+}
+
+// Prices returns the oil and gas prices, as drawn so far this week.
+func (t Turn) Prices() (oil, gas int) {
+	return t.g.price, t.g.gasPrice
+}
+
+// Record applies a change to the game and writes it in the journal, so the
+// game replays as it was played.
+func (t Turn) Record(c Change) {
+	t.g.record(c)
+}
+
+// defaultSystems returns the game's rules in the order they run each week.
+// events come before production so a well that blows out produces nothing,
+// and production before tax so wells are paid for what they produced.
+func defaultSystems() []namedSystem {
+	return []namedSystem{
+		{"price", priceSystem},
+		{"events", eventSystem},
+		{"production", productionSystem},
+		{"tax", taxSystem},
+		{"surveyors", surveyorSystem},
+	}
+}
+
+// WithSystem replaces the named system that runs at the turn of each week,
+// or adds it after the others if the game has no system by that name. A nil
+// System removes it. The built-in systems are price, events, production, tax
+// and surveyors, in that order.
+func WithSystem(name string, s System) Option {
+	return func(g *game) {
+		for i, ns := range g.systems {
+			if ns.name != name {
+				continue
+			}
+			if s == nil {
+				g.systems = append(g.systems[:i:i], g.systems[i+1:]...)
+			} else {
+				g.systems[i].run = s.run
+			}
+			return
+		}
+		if s != nil {
+			g.systems = append(g.systems, namedSystem{name, s.run})
+		}
+	}
+}
+
+// WithSystemBefore adds a System to run just before the named one, or after
+// all the others if there's no system by that name.
+func WithSystemBefore(next, name string, s System) Option {
+	return func(g *game) {
+		for i, ns := range g.systems {
+			if ns.name == next {
+				systems := append(g.systems[:i:i], namedSystem{name, s.run})
+				g.systems = append(systems, g.systems[i:]...)
+				return
+			}
+		}
+		g.systems = append(g.systems, namedSystem{name, s.run})
+	}
+}
+
+func (s System) run(g *game) {
+	s(Turn{g})
+}
+
+// priceSystem draws the week's oil and gas prices.
+func priceSystem(g *game) {
+	g.record(Prices{
+		Oil: int(100 * math.Abs(1+rand.NormFloat64())),
+		Gas: int(gasPrice * math.Abs(1+0.5*rand.NormFloat64())),
+	})
+}
+
+// productionSystem draws down the reservoirs and credits each well with its
+// oil and gas.
+func productionSystem(g *game) {
+	oil, gas := g.produce(false), g.produce(true)
+
+	g.world.EachWell(func(e entity, p *production) {
+		if !g.producing(e) {
+			return
+		}
+		c := Produced{Site: g.world.Location(e)}
+		if p.gas {
+			c.GasOutput = gas[e]
+		} else {
+			// oil comes up with gas dissolved in it
+			c.Output = oil[e]
+			c.GasOutput = c.Output * gasOilRatio
+		}
+		g.record(c)
+	})
+}
+
+// taxSystem credits each well's owner with a week of income less taxes.
+func taxSystem(g *game) {
+	// real-time games accrue income and taxes daily instead
+	if g.mode == Realtime {
+		return
+	}
+	g.world.EachWell(func(e entity, p *production) {
+		if g.producing(e) {
+			g.record(g.earnings(e))
+		}
+	})
+}
+
+// chance a producing well blows out in any week
+const blowoutChance = 0.01
+
+// An Incident is something that happened in the field at the turn of the week.
+type Incident struct {
+	Kind string `json:"kind"`
+	Site site   `json:"site"`
+}
+
+// eventSystem strikes wells with mishaps. a well that blows out is shut in
+// for the week: it produces nothing but still pays its taxes.
+func eventSystem(g *game) {
+	g.world.EachWell(func(e entity, p *production) {
+		if g.producing(e) && rand.Float64() < blowoutChance {
+			g.blowout(e)
+		}
+	})
+}
+
+func (g *game) blowout(e entity) {
+	s := g.world.Location(e)
+	log.Printf("well at site %d blew out in week %d", s, g.week)
+	g.record(BlewOut{s})
+}
+
+// surveyorSystem lets every player survey again.
+func surveyorSystem(g *game) {
+	// sequential games appoint each surveyor as their turn begins
+	if g.mode == Sequential {
+		return
+	}
+	for _, player := range g.world.Players() {
+		g.world.SetSurveyor(player)
+	}
+}
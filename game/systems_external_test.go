@@ -0,0 +1,56 @@
+package game_test
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/9r33n/wildcatting/game"
+)
+
+func TestScenarioSystems(t *testing.T) {
+	var weeks []int
+	fixedPrice := func(t game.Turn) {
+		t.Record(game.Prices{Oil: 50, Gas: 3})
+	}
+	inflation := func(t game.Turn) {
+		weeks = append(weeks, t.Week())
+		oil, gas := t.Prices()
+		t.Record(game.Prices{Oil: 2 * oil, Gas: 2 * gas})
+	}
+
+	g := game.New(
+		game.WithMap(game.Map{
+			Height: 1,
+			Width:  2,
+			Prob:   []int{50, 50},
+			Cost:   []int{10, 10},
+			Tax:    []int{100, 100},
+			Oil:    [][]int{{2}, nil},
+		}),
+		game.WithSystem("price", fixedPrice),
+		game.WithSystem("events", nil),
+		game.WithSystemBefore("production", "inflation", inflation),
+	)
+	defer g.Close()
+
+	ctx := context.Background()
+	bob, err := g.Join(ctx, "bob")
+	if err != nil {
+		t.Fatalf("Join -> %s", err)
+	}
+	if _, err := g.Move(ctx, bob, -1); err != nil {
+		t.Fatalf("Move -> %s", err)
+	}
+
+	var prices []game.Prices
+	for _, e := range g.Journal(1) {
+		if p, ok := e.Change.(game.Prices); ok {
+			prices = append(prices, p)
+		}
+	}
+	expect := []game.Prices{{Oil: 50, Gas: 3}, {Oil: 100, Gas: 6}}
+	if !reflect.DeepEqual(prices, expect) || !reflect.DeepEqual(weeks, []int{1}) {
+		t.Errorf("week %v prices %v; expect week 1 prices %v", weeks, prices, expect)
+	}
+}
@@ -0,0 +1,121 @@
+package game
+
+import (
+	"reflect"
+	"testing"
+)
+
+func systemNames(g *game) []string {
+	var names []string
+	for _, s := range g.systems {
+		names = append(names, s.name)
+	}
+	return names
+}
+
+func TestWithSystem(t *testing.T) {
+	var ran []string
+	record := func(name string) System {
+		return func(Turn) { ran = append(ran, name) }
+	}
+
+	g := &game{systems: defaultSystems()}
+	WithSystem("price", record("fixed price"))(g)
+	WithSystem("events", nil)(g)
+	WithSystem("inflation", record("inflation"))(g)
+	WithSystemBefore("tax", "royalties", record("royalties"))(g)
+
+	expect := []string{"price", "production", "royalties", "tax", "surveyors", "inflation"}
+	if names := systemNames(g); !reflect.DeepEqual(names, expect) {
+		t.Fatalf("systems %v; expect %v", names, expect)
+	}
+
+	g = newTestGame(&field{height: 1, width: 1, oil: [][]int{nil}})
+	g.systems = nil
+	WithSystem("a", record("a"))(g)
+	WithSystemBefore("a", "b", record("b"))(g)
+	g.nextWeek()
+	if expect := []string{"b", "a"}; !reflect.DeepEqual(ran, expect) || g.week != 1 {
+		t.Errorf("week %d ran %v; expect week 1 ran %v", g.week, ran, expect)
+	}
+}
+
+func TestPriceSystem(t *testing.T) {
+	g := newTestGame(&field{})
+	for i := 0; i < 100; i++ {
+		priceSystem(g)
+		if g.price < 0 || g.gasPrice < 0 {
+			t.Fatalf("negative prices %d and %d", g.price, g.gasPrice)
+		}
+	}
+}
+
+func TestProductionSystem(t *testing.T) {
+	f := &field{
+		height: 1,
+		width:  2,
+		oil:    [][]int{{2}, {2}},
+		tax:    []int{100, 100},
+	}
+	g := newTestGame(f)
+	g.week = 2
+	e := addWell(g, 0, 1, 2)
+	sold := addWell(g, 1, 1, 2)
+	g.world.Sell(sold, 2)
+
+	productionSystem(g)
+	p := g.world.Production(e)
+	if p.output == 0 || p.gasOutput != p.output*gasOilRatio {
+		t.Errorf("expect oil with associated gas; got %d barrels and %d mcf", p.output, p.gasOutput)
+	}
+	if g.produced[0] != p.output || g.world.Production(sold).output != 0 {
+		t.Errorf("reservoir produced %d; expect only the unsold well's %d", g.produced[0], p.output)
+	}
+
+	// a blown out well is shut in for the week
+	g.week++
+	g.blowout(e)
+	productionSystem(g)
+	if p.output != 0 || len(g.incidents) != 1 || g.incidents[0] != (Incident{"blowout", 0}) {
+		t.Errorf("blowout: expect no output and an incident; got %d barrels and %v", p.output, g.incidents)
+	}
+}
+
+func TestTaxSystem(t *testing.T) {
+	f := &field{
+		height: 1,
+		width:  1,
+		oil:    [][]int{{2}},
+		tax:    []int{100},
+	}
+	g := newTestGame(f)
+	g.price = 100
+	e := addWell(g, 0, 1, 2)
+	g.world.Production(e).output = 50
+
+	taxSystem(g)
+	if pnl := g.world.Owner(e).pnl; pnl != 50-100 {
+		t.Errorf("pnl %d; expect %d", pnl, 50-100)
+	}
+
+	// real-time games accrue daily
+	g.mode = Realtime
+	taxSystem(g)
+	if pnl := g.world.Owner(e).pnl; pnl != 50-100 {
+		t.Errorf("real-time pnl %d; expect it unchanged", pnl)
+	}
+}
+
+func TestSurveyorSystem(t *testing.T) {
+	for _, mode := range []Mode{Simultaneous, Sequential} {
+		g := newTestGame(&field{})
+		g.mode = mode
+		p := g.world.NewEntity()
+		g.world.AddPlayer(p)
+
+		surveyorSystem(g)
+		if g.world.IsSurveyor(p) != (mode != Sequential) {
+			t.Errorf("mode %d: surveyor %t", mode, g.world.IsSurveyor(p))
+		}
+	}
+}
@@ -0,0 +1,147 @@
+package game
+
+import "fmt"
+
+// Grid selects which sites of a field are adjacent to each other.
+type Grid int
+
+const (
+	// Square4 grids connect each site to the four sites beside it.
+	Square4 Grid = iota
+	// Square8 grids also connect each site to the four sites diagonal
+	// to it.
+	Square8
+	// Hex grids shift every odd row right by half a site, connecting each
+	// site to two neighbors in its own row and two in each row beside it.
+	Hex
+)
+
+var grids = map[string]Grid{
+	"":        Square4,
+	"square4": Square4,
+	"square8": Square8,
+	"hex":     Hex,
+}
+
+// ParseGrid returns the Grid with the given name. The empty name is Square4.
+func ParseGrid(name string) (Grid, error) {
+	grid, ok := grids[name]
+	if !ok {
+		return 0, fmt.Errorf("unknown grid %q", name)
+	}
+	return grid, nil
+}
+
+func (g Grid) String() string {
+	switch g {
+	case Square8:
+		return "square8"
+	case Hex:
+		return "hex"
+	}
+	return "square4"
+}
+
+func (g Grid) MarshalText() ([]byte, error) {
+	return []byte(g.String()), nil
+}
+
+func (g *Grid) UnmarshalText(text []byte) error {
+	grid, err := ParseGrid(string(text))
+	if err != nil {
+		return err
+	}
+	*g = grid
+	return nil
+}
+
+// Topology describes how a field's sites connect: the adjacency of its grid,
+// and whether its edges wrap around to meet the opposite edges. Wrapped hex
+// grids need an even height so that row offsets line up across the seam.
+// The zero Topology is the classic unwrapped Square4 grid.
+type Topology struct {
+	Grid Grid `json:"grid"`
+	Wrap bool `json:"wrap,omitempty"`
+}
+
+// offsets returns the row and column offsets of a site's neighbors in the
+// given row.
+func (t Topology) offsets(y int) [][2]int {
+	switch t.Grid {
+	case Square8:
+		return [][2]int{{-1, -1}, {-1, 0}, {-1, 1}, {0, -1}, {0, 1}, {1, -1}, {1, 0}, {1, 1}}
+	case Hex:
+		if y%2 == 0 {
+			return [][2]int{{-1, -1}, {-1, 0}, {0, -1}, {0, 1}, {1, -1}, {1, 0}}
+		}
+		return [][2]int{{-1, 0}, {-1, 1}, {0, -1}, {0, 1}, {1, 0}, {1, 1}}
+	}
+	return [][2]int{{-1, 0}, {0, -1}, {0, 1}, {1, 0}}
+}
+
+// neighbors returns the sites adjacent to s on a field of the given size.
+func (t Topology) neighbors(height, width int, s site) []site {
+	y, x := int(s)/width, int(s)%width
+	offsets := t.offsets(y)
+	nbrs := make([]site, 0, len(offsets))
+next:
+	for _, d := range offsets {
+		ny, nx := y+d[0], x+d[1]
+		if t.Wrap {
+			ny, nx = (ny+height)%height, (nx+width)%width
+		} else if ny < 0 || ny >= height || nx < 0 || nx >= width {
+			continue
+		}
+		nbr := site(ny*width + nx)
+		// tiny wrapped fields can reach the same site, or themselves, twice
+		if nbr == s {
+			continue
+		}
+		for _, n := range nbrs {
+			if n == nbr {
+				continue next
+			}
+		}
+		nbrs = append(nbrs, nbr)
+	}
+	return nbrs
+}
+
+// distance returns the number of steps between sites p and q on a field of
+// the given size.
+func (t Topology) distance(height, width, p, q int) int {
+	py, px := p/width, p%width
+	qy, qx := q/width, q%width
+	if !t.Wrap {
+		return t.steps(py, px, qy, qx)
+	}
+
+	// the shortest way round may cross either seam
+	min := height + width
+	for _, dy := range []int{-height, 0, height} {
+		for _, dx := range []int{-width, 0, width} {
+			if d := t.steps(py, px, qy+dy, qx+dx); d < min {
+				min = d
+			}
+		}
+	}
+	return min
+}
+
+// steps returns the distance between two points on an unbounded grid.
+func (t Topology) steps(py, px, qy, qx int) int {
+	dy, dx := abs(py-qy), abs(px-qx)
+	switch t.Grid {
+	case Square8:
+		if dy > dx {
+			return dy
+		}
+		return dx
+	case Hex:
+		// convert odd-r offset coordinates to axial ones
+		pq, qq := px-(py-(py&1))/2, qx-(qy-(qy&1))/2
+		dq, dr := pq-qq, py-qy
+		return (abs(dq) + abs(dr) + abs(dq+dr)) / 2
+	}
+	return dy + dx
+}
@@ -0,0 +1,124 @@
+package game
+
+import (
+	"reflect"
+	"sort"
+	"testing"
+)
+
+var neighborTests = []struct {
+	topo   Topology
+	height int
+	width  int
+	site   site
+	expect []site
+}{
+	// 0 1 2
+	// 3 4 5
+	// 6 7 8
+	{Topology{Square4, false}, 3, 3, 0, []site{1, 3}},
+	{Topology{Square4, true}, 3, 3, 0, []site{1, 2, 3, 6}},
+	{Topology{Square8, false}, 3, 3, 0, []site{1, 3, 4}},
+	{Topology{Square8, false}, 3, 3, 4, []site{0, 1, 2, 3, 5, 6, 7, 8}},
+	{Topology{Square8, true}, 3, 3, 0, []site{1, 2, 3, 4, 5, 6, 7, 8}},
+
+	//  0   1   2   3
+	//    4   5   6   7
+	//  8   9  10  11
+	//   12  13  14  15
+	{Topology{Hex, false}, 4, 4, 5, []site{1, 2, 4, 6, 9, 10}},
+	{Topology{Hex, false}, 4, 4, 9, []site{4, 5, 8, 10, 12, 13}},
+	{Topology{Hex, false}, 4, 4, 0, []site{1, 4}},
+	{Topology{Hex, true}, 4, 4, 0, []site{1, 3, 4, 7, 12, 15}},
+	{Topology{Hex, true}, 4, 4, 7, []site{0, 3, 4, 6, 8, 11}},
+
+	// wrapping a single row reaches the same site both ways, and never itself
+	{Topology{Square4, true}, 1, 2, 0, []site{1}},
+}
+
+func TestTopologyNeighbors(t *testing.T) {
+	for _, test := range neighborTests {
+		nbrs := test.topo.neighbors(test.height, test.width, test.site)
+		sort.Slice(nbrs, func(i, j int) bool { return nbrs[i] < nbrs[j] })
+		if !reflect.DeepEqual(nbrs, test.expect) {
+			t.Errorf("%+v %dx%d: neighbors(%d) -> %v; expect %v", test.topo, test.height, test.width, test.site, nbrs, test.expect)
+		}
+	}
+}
+
+func TestTopologyDistance(t *testing.T) {
+	topos := []Topology{
+		{Square4, false}, {Square4, true},
+		{Square8, false}, {Square8, true},
+		{Hex, false}, {Hex, true},
+	}
+	height, width := 6, 7
+
+	for _, topo := range topos {
+		// neighbors are one step apart, and distance is symmetric
+		for p := 0; p < height*width; p++ {
+			for _, q := range topo.neighbors(height, width, site(p)) {
+				if d := topo.distance(height, width, p, int(q)); d != 1 {
+					t.Errorf("%+v: distance(%d, %d) -> %d; expect 1", topo, p, q, d)
+				}
+			}
+			for q := 0; q < height*width; q++ {
+				if d, e := topo.distance(height, width, p, q), topo.distance(height, width, q, p); d != e {
+					t.Errorf("%+v: distance(%d, %d) -> %d; distance(%d, %d) -> %d", topo, p, q, d, q, p, e)
+				}
+			}
+		}
+	}
+
+	// corners are far apart unless the field wraps
+	far := height*width - 1
+	if d := (Topology{Square4, false}).distance(height, width, 0, far); d != 11 {
+		t.Errorf("square4: distance(0, %d) -> %d; expect 11", far, d)
+	}
+	if d := (Topology{Square4, true}).distance(height, width, 0, far); d != 2 {
+		t.Errorf("wrapped square4: distance(0, %d) -> %d; expect 2", far, d)
+	}
+	if d := (Topology{Square8, false}).distance(height, width, 0, far); d != 6 {
+		t.Errorf("square8: distance(0, %d) -> %d; expect 6", far, d)
+	}
+}
+
+func TestTopologyReservoirs(t *testing.T) {
+	// a diagonal seam of oil
+	oil := [][]int{
+		{1}, nil, nil,
+		nil, {1}, nil,
+		nil, nil, {1},
+	}
+	tests := []struct {
+		topo   Topology
+		expect int
+	}{
+		{Topology{Square4, false}, 1},
+		{Topology{Square8, false}, 3},
+		{Topology{Hex, false}, 2},
+	}
+
+	for _, test := range tests {
+		f := &field{height: 3, width: 3, topo: test.topo, oil: oil}
+		if n := len(f.reservoir(4, 1)); n != test.expect {
+			t.Errorf("%+v: len(reservoir(4, 1)) -> %d; expect %d", test.topo, n, test.expect)
+		}
+	}
+}
+
+func TestParseGrid(t *testing.T) {
+	for name, expect := range grids {
+		grid, err := ParseGrid(name)
+		if err != nil || grid != expect {
+			t.Errorf("ParseGrid(%q) -> %v, %v; expect %v", name, grid, err, expect)
+		}
+		text, _ := grid.MarshalText()
+		if name != "" && string(text) != name {
+			t.Errorf("%v.MarshalText() -> %q; expect %q", grid, text, name)
+		}
+	}
+	if _, err := ParseGrid("triangle"); err == nil {
+		t.Errorf("ParseGrid(\"triangle\") -> nil error")
+	}
+}
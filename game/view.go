@@ -1,6 +1,9 @@
 package game
 
-import "math/rand"
+import (
+	"math/rand"
+	"time"
+)
 
 // View is a generic type for JSON serializable data representing the client state.
 type View interface{}
@@ -13,14 +16,7 @@ func lobbyView(g *game) View {
 
 	players := make([]player, 0)
 	for _, p := range g.world.Players() {
-		pnl := 0
-		for _, deed := range g.deeds {
-			if deed.player != entity(p) {
-				continue
-			}
-			pnl += deed.pnl
-		}
-		players = append(players, player{g.world.Name(p), pnl})
+		players = append(players, player{g.world.Name(p), g.pnl(p)})
 	}
 
 	return struct {
@@ -30,97 +26,271 @@ func lobbyView(g *game) View {
 	}{"lobby", g.week, players}
 }
 
+// summaryView shows how every player fared over the week just finished:
+// what they spent drilling, what they found, and what their wells earned
+// and paid in taxes at the turn of the week.
+func summaryView(g *game) View {
+	type player struct {
+		Name        string `json:"name"`
+		Income      int    `json:"income"`
+		Transport   int    `json:"transport"`
+		Taxes       int    `json:"taxes"`
+		Drilling    int    `json:"drilling"`
+		Discoveries int    `json:"discoveries"`
+		DryHoles    int    `json:"dryHoles"`
+		PNL         int    `json:"pnl"`
+	}
+
+	last := g.week - 1
+	players := make([]player, 0)
+	for _, p := range g.world.Players() {
+		pl := player{Name: g.world.Name(p), PNL: g.pnl(p)}
+		for _, e := range g.world.Owned(p) {
+			drilled := false
+			for _, entry := range g.world.Owner(e).ledger {
+				switch {
+				case entry.Week == g.week && (entry.Type == incomeEntry || entry.Type == gasIncomeEntry):
+					pl.Income += entry.Amount
+				case entry.Week == g.week && entry.Type == transportEntry:
+					pl.Transport -= entry.Amount
+				case entry.Week == g.week && entry.Type == taxEntry:
+					pl.Taxes -= entry.Amount
+				case entry.Week == last && (entry.Type == drillingEntry || entry.Type == remobilizationEntry):
+					pl.Drilling -= entry.Amount
+					drilled = true
+				}
+			}
+			p := g.world.Production(e)
+			if p != nil && p.struck == last {
+				pl.Discoveries++
+			}
+			if drilled && p == nil {
+				pl.DryHoles++
+			}
+		}
+		players = append(players, pl)
+	}
+
+	return struct {
+		Name         string   `json:"name"`
+		Week         int      `json:"week"`
+		Price        int      `json:"price"`
+		PrevPrice    int      `json:"prevPrice"`
+		GasPrice     int      `json:"gasPrice"`
+		PrevGasPrice int      `json:"prevGasPrice"`
+		Players      []player `json:"players"`
+	}{"summary", last, g.price, g.prevPrice, g.gasPrice, g.prevGasPrice, players}
+}
+
 func playView(g *game) View {
+	var turn string
+	if g.mode == Sequential {
+		turn = g.world.Name(g.turn)
+	}
+
+	var day int
+	if g.mode == Realtime {
+		day = g.day + 1
+	}
+
+	var deadline *time.Time
+	if g.mode == Async {
+		deadline = &g.deadline
+	}
+
 	return struct {
-		Name string `json:"name"`
-		Week int    `json:"week"`
-	}{"play", g.week}
+		Name     string     `json:"name"`
+		Week     int        `json:"week"`
+		Turn     string     `json:"turn,omitempty"`
+		Day      int        `json:"day,omitempty"`
+		Deadline *time.Time `json:"deadline,omitempty"`
+	}{"play", g.week, turn, day, deadline}
 }
 
 type playerViewFn func(*game, entity) View
 
+func waitView(g *game, playerID entity) View {
+	order := make([]string, 0)
+	for _, p := range g.turnOrder() {
+		order = append(order, g.world.Name(p))
+	}
+
+	return struct {
+		Name  string   `json:"name"`
+		Week  int      `json:"week"`
+		Turn  string   `json:"turn"`
+		Order []string `json:"order"`
+	}{"wait", g.week, g.world.Name(g.turn), order}
+}
+
 func surveyView(g *game, playerID entity) View {
 	return struct {
-		Name  string `json:"name"`
-		Week  int    `json:"week"`
-		Price int    `json:"price"`
-		Prob  []int  `json:"prob"`
-		Cost  []int  `json:"cost"`
-		Tax   []int  `json:"tax"`
-		Oil   []int  `json:"oil"`
-		Fact  string `json:"fact"`
-	}{"survey", g.week, g.price, g.f.prob, g.f.cost, g.f.tax, g.f.oil, facts[rand.Intn(len(facts))]}
+		Name     string    `json:"name"`
+		Week     int       `json:"week"`
+		Price    int       `json:"price"`
+		GasPrice int       `json:"gasPrice"`
+		Height   int       `json:"height"`
+		Width    int       `json:"width"`
+		Topology Topology  `json:"topology"`
+		Prob     []int     `json:"prob"`
+		Cost     []int     `json:"cost"`
+		Tax      []int     `json:"tax"`
+		Oil      []int     `json:"oil"`
+		Surface  []Feature `json:"surface,omitempty"`
+		Fact     string    `json:"fact"`
+	}{"survey", g.week, g.price, g.gasPrice, g.f.height, g.f.width, g.f.topo, g.f.prob, g.f.cost, g.f.tax, g.f.shallowest(), g.f.surface, facts[rand.Intn(len(facts))]}
 }
 
 func reportView(g *game, playerID entity, siteID site) View {
+	var surface Feature
+	if g.f.surface != nil {
+		surface = g.f.surface[siteID]
+	}
+
 	return struct {
-		Name string `json:"name"`
-		Site site   `json:"site"`
-		Prob int    `json:"prob"`
-		Cost int    `json:"cost"`
-		Tax  int    `json:"tax"`
-	}{"report", siteID, g.f.prob[siteID], g.f.cost[siteID], g.f.tax[siteID]}
+		Name      string  `json:"name"`
+		Site      site    `json:"site"`
+		Prob      int     `json:"prob"`
+		Cost      int     `json:"cost"`
+		Tax       int     `json:"tax"`
+		Surface   Feature `json:"surface"`
+		Transport int     `json:"transport"`
+	}{"report", siteID, g.f.prob[siteID], g.f.cost[siteID], g.f.tax[siteID], surface, g.f.transport(siteID)}
+}
+
+// resourceName names what a well produces.
+func resourceName(gas bool) string {
+	if gas {
+		return "gas"
+	}
+	return "oil"
+}
+
+func completeView(g *game, playerID entity, siteID site) View {
+	bit := g.world.DrillState(g.deed(siteID)).bit
+	_, pipeline := g.f.pipelineAccess(siteID)
+	return struct {
+		Name     string `json:"name"`
+		Site     site   `json:"site"`
+		Resource string `json:"resource"`
+		Depth    int    `json:"depth"`
+		Cost     int    `json:"cost"`
+		Final    bool   `json:"final"`
+		Pipeline bool   `json:"pipeline"`
+	}{"complete", siteID, resourceName(!g.f.zone(siteID, bit)), bit * 100, bit * g.f.cost[siteID], bit == maxOil, pipeline}
+}
+
+func connectView(g *game, playerID entity, siteID site) View {
+	fee, _ := g.f.pipelineAccess(siteID)
+	return struct {
+		Name     string `json:"name"`
+		Site     site   `json:"site"`
+		Resource string `json:"resource"`
+		Fee      int    `json:"fee"`
+		GasPrice int    `json:"gasPrice"`
+	}{"connect", siteID, resourceName(g.world.Production(g.deed(siteID)).gas), fee, g.gasPrice}
+}
+
+func reenterView(g *game, playerID entity, siteID site) View {
+	return struct {
+		Name  string `json:"name"`
+		Site  site   `json:"site"`
+		Depth int    `json:"depth"`
+		Cost  int    `json:"cost"`
+		Fee   int    `json:"fee"`
+	}{"reenter", siteID, g.world.DrillState(g.deed(siteID)).bit * 100, g.f.cost[siteID], g.remobilizeFee(siteID)}
+}
+
+func workoverView(g *game, playerID entity, siteID site) View {
+	return struct {
+		Name   string `json:"name"`
+		Site   site   `json:"site"`
+		Output int    `json:"output"`
+		Fee    int    `json:"fee"`
+	}{"workover", siteID, g.world.Production(g.deed(siteID)).output, g.workoverFee(siteID)}
 }
 
 func drillView(siteID site) playerViewFn {
 	return func(g *game, playerID entity) View {
-		depth := g.deeds[siteID].bit * 100
-		cost := g.deeds[siteID].bit * g.f.cost[siteID]
+		bit := g.world.DrillState(g.deed(siteID)).bit
+		depth := bit * 100
+		cost := bit * g.f.cost[siteID]
 		return struct {
-			Name  string `json:"name"`
-			Depth int    `json:"depth"`
-			Cost  int    `json:"cost"`
-		}{"drill", depth, cost}
+			Name    string `json:"name"`
+			Depth   int    `json:"depth"`
+			Cost    int    `json:"cost"`
+			Limited bool   `json:"limited,omitempty"`
+		}{"drill", depth, cost, !g.canDrill(playerID)}
 	}
 }
 
 type well struct {
-	Week   int  `json:"week"`
-	SiteID site `json:"site"`
-	Sold   bool `json:"sold"`
-	Depth  int  `json:"depth"`
-	Cost   int  `json:"cost"`
-	Tax    int  `json:"tax"`
-	Income int  `json:"income"`
-	PNL    int  `json:"pnl"`
+	Week      int  `json:"week"`
+	SiteID    site `json:"site"`
+	Sold      bool `json:"sold"`
+	Depth     int  `json:"depth"`
+	Cost      int  `json:"cost"`
+	Tax       int  `json:"tax"`
+	Income    int  `json:"income"`
+	Transport int  `json:"transport"`
+	Output    int  `json:"output"`
+	Barrels   int  `json:"barrels"`
+	Gas       bool `json:"gas"`
+	GasIncome int  `json:"gasIncome"`
+	GasOutput int  `json:"gasOutput"`
+	Flared    bool `json:"flared"`
+	PNL       int  `json:"pnl"`
 }
 
 func wellsView(g *game, playerID entity) View {
+	state := struct {
+		Name      string     `json:"name"`
+		Player    string     `json:"player"`
+		Week      int        `json:"week"`
+		Price     int        `json:"price"`
+		GasPrice  int        `json:"gasPrice"`
+		Wells     []well     `json:"wells"`
+		Incidents []Incident `json:"incidents"`
+	}{"wells", g.world.Name(playerID), g.week, g.price, g.gasPrice, wellList(g, playerID), g.incidents}
+	return state
+}
+
+// wellList returns the player's deeds, one for each week.
+func wellList(g *game, playerID entity) []well {
 	wells := make([]well, g.week)
-	for s, deed := range g.deeds {
-		if deed.player != playerID {
-			continue
-		}
+	for _, e := range g.world.Owned(playerID) {
+		s, o, bit := g.world.Location(e), g.world.Owner(e), g.world.DrillState(e).bit
 
 		var tax int
-		if deed.bit > 0 {
+		if bit > 0 {
 			tax = g.f.tax[s]
 		}
 
 		well := well{
-			Week:   deed.week,
+			Week:   o.week,
 			SiteID: s,
-			Sold:   deed.stop > 0,
-			Cost:   g.f.cost[s] * deed.bit, // cost is in cents and bit is in 100 ft increments so they cancel out
+			Sold:   g.world.Sold(e) > 0,
+			Cost:   g.f.cost[s] * bit, // cost is in cents and bit is in 100 ft increments so they cancel out
 			Tax:    tax,
-			Income: deed.output * g.price / 100,
-			PNL:    deed.pnl,
+			PNL:    o.pnl,
 		}
-		// players only know about oil if it was reached with the bit
-		if deed.bit == g.f.oil[s] {
-			well.Depth = g.f.oil[s] * 100
+		// players only know about oil or gas if they completed a well in it
+		if p := g.world.Production(e); p != nil {
+			well.Depth = p.zone * 100
+			well.Income = p.output * g.price / 100
+			well.Transport = p.output * g.f.transport(s) / 100
+			well.Output = p.output
+			well.Barrels = p.produced
+			well.Gas = p.gas
+			well.GasOutput = p.gasOutput
+			well.Flared = p.gasOutput > 0 && !p.connected
+			if p.connected {
+				well.GasIncome = p.gasOutput * g.gasPrice / 100
+			}
 		}
-		wells[deed.week-1] = well
+		wells[o.week-1] = well
 	}
-
-	state := struct {
-		Name   string `json:"name"`
-		Player string `json:"player"`
-		Week   int    `json:"week"`
-		Price  int    `json:"price"`
-		Wells  []well `json:"wells"`
-	}{"wells", g.world.Name(playerID), g.week, g.price, wells}
-	return state
+	return wells
 }
 
 func scoreView(g *game, playerID entity) View {
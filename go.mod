@@ -0,0 +1,5 @@
+module github.com/9r33n/wildcatting
+
+go 1.21
+
+require github.com/gorilla/mux v1.8.1
@@ -0,0 +1,127 @@
+package main
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+)
+
+// list a game's journal, or one week of it
+func (h *handler) getJournal(w http.ResponseWriter, r *http.Request) {
+	gameID, err := strconv.Atoi(mux.Vars(r)["gid"])
+	if err != nil {
+		// mux should guarantee a parsable int
+		panic(err)
+	}
+	g, ok := h.game(gameID)
+	if !ok {
+		http.Error(w, "game not found", http.StatusNotFound)
+		return
+	}
+
+	var week int
+	if s := r.URL.Query().Get("week"); s != "" {
+		if week, err = strconv.Atoi(s); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+	writeJSON(w, g.Journal(week))
+}
+
+// replay a game's journal to the end of a week
+func (h *handler) getStandings(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	gameID, err := strconv.Atoi(vars["gid"])
+	if err != nil {
+		// mux should guarantee a parsable int
+		panic(err)
+	}
+	g, ok := h.game(gameID)
+	if !ok {
+		http.Error(w, "game not found", http.StatusNotFound)
+		return
+	}
+	week, err := strconv.Atoi(vars["week"])
+	if err != nil {
+		panic(err)
+	}
+
+	standings, err := g.Standings(week)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	writeJSON(w, standings)
+}
+
+// a whole game in one document, for watching it back
+func (h *handler) getReplay(w http.ResponseWriter, r *http.Request) {
+	gameID, err := strconv.Atoi(mux.Vars(r)["gid"])
+	if err != nil {
+		// mux should guarantee a parsable int
+		panic(err)
+	}
+	g, ok := h.game(gameID)
+	if !ok {
+		http.Error(w, "game not found", http.StatusNotFound)
+		return
+	}
+
+	timeline, err := g.Timeline()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, timeline)
+}
+
+// finished serves a game's history to players only once the game is over,
+// since it shows where the oil is.
+func (h *handler) finished(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		gameID, err := strconv.Atoi(mux.Vars(r)["gid"])
+		if err != nil {
+			// mux should guarantee a parsable int
+			panic(err)
+		}
+		g, ok := h.game(gameID)
+		if !ok {
+			http.Error(w, "game not found", http.StatusNotFound)
+			return
+		}
+		select {
+		case <-g.Done():
+			next(w, r)
+		default:
+			http.Error(w, "game still in play", http.StatusForbidden)
+		}
+	}
+}
+
+// every deed in a game as it stood at the end of a week
+func (h *handler) getReplayWeek(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	gameID, err := strconv.Atoi(vars["gid"])
+	if err != nil {
+		// mux should guarantee a parsable int
+		panic(err)
+	}
+	g, ok := h.game(gameID)
+	if !ok {
+		http.Error(w, "game not found", http.StatusNotFound)
+		return
+	}
+	week, err := strconv.Atoi(vars["week"])
+	if err != nil {
+		panic(err)
+	}
+
+	frame, err := g.Frame(week)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	writeJSON(w, frame)
+}
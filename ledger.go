@@ -0,0 +1,57 @@
+package main
+
+import (
+	"encoding/csv"
+	"net/http"
+	"strconv"
+
+	"github.com/9r33n/wildcatting/game"
+	"github.com/gorilla/mux"
+)
+
+// export a player's itemized accounts, or one deed's with ?site=n
+func (h *handler) getLedger(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	gameID, err := strconv.Atoi(vars["gid"])
+	if err != nil {
+		// mux should guarantee a parsable int
+		panic(err)
+	}
+	playerID, err := strconv.Atoi(vars["pid"])
+	if err != nil {
+		panic(err)
+	}
+	g, ok := h.game(gameID)
+	if !ok {
+		http.Error(w, "game not found", http.StatusNotFound)
+		return
+	}
+
+	entries := g.Ledger(playerID)
+	if s := r.URL.Query().Get("site"); s != "" {
+		site, err := strconv.Atoi(s)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		var deed []game.LedgerEntry
+		for _, e := range entries {
+			if int(e.Site) == site {
+				deed = append(deed, e)
+			}
+		}
+		entries = deed
+	}
+
+	if vars["format"] == "json" {
+		writeJSON(w, entries)
+		return
+	}
+	w.Header().Set("Content-Type", "text/csv")
+	cw := csv.NewWriter(w)
+	cw.Write([]string{"week", "site", "type", "amount"})
+	for _, e := range entries {
+		cw.Write([]string{strconv.Itoa(e.Week), strconv.Itoa(int(e.Site)), e.Type, strconv.Itoa(e.Amount)})
+	}
+	cw.Flush()
+}
@@ -1,14 +1,18 @@
 package main
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"expvar"
 	"flag"
 	"fmt"
+	"io"
 	"log"
 	"net/http"
 	"runtime"
 	"strconv"
+	"sync"
 	"time"
 
 	"github.com/9r33n/wildcatting/game"
@@ -16,26 +20,40 @@ import (
 )
 
 var (
-	debug = flag.String("debug", "", "run expvar/pprof server (host:port)")
-	stats = expvar.NewMap("wildcatting")
+	debug      = flag.String("debug", "", "run expvar/pprof and admin server (host:port)")
+	mapsDir    = flag.String("maps", "", "directory to store maps in")
+	archiveDir = flag.String("archive", "", "directory to archive closed games in")
+	timeout    = flag.Duration("timeout", 10*time.Second, "how long to wait on a game before giving up on a request")
+	stats      = expvar.NewMap("wildcatting")
 )
 
 type handler struct {
-	games []game.Game
+	// games are nil once they're archived
+	sync.RWMutex
+	games   []game.Game
+	maps    *mapStore
+	archive *archive
 }
 
 func main() {
 	flag.Parse()
 	publishRuntime()
 
+	maps, err := newMapStore(*mapsDir)
+	if err != nil {
+		log.Fatal(err)
+	}
+	h := &handler{maps: maps, archive: newArchive(*archiveDir)}
+	if err := h.resume(); err != nil {
+		log.Fatal(err)
+	}
+
 	if *debug != "" {
 		go func() {
-			log.Fatal(http.ListenAndServe(*debug, nil))
+			log.Fatal(http.ListenAndServe(*debug, h.newAdminRouter()))
 		}()
 	}
 
-	h := &handler{}
-
 	host := "0.0.0.0"
 	port := 8888
 	addr := fmt.Sprintf("%s:%d", host, port)
@@ -57,6 +75,12 @@ func (h *handler) newRouter() *mux.Router {
 		route{"GET", "/game/{gid:[0-9]+}/", h.getGameID},
 		route{"POST", "/game/{gid:[0-9]+}/player/{pid:[0-9]+}/", h.postPlayerID},
 		route{"GET", "/game/{gid:[0-9]+}/player/{pid:[0-9]+}/", h.getPlayerID},
+		route{"GET", "/game/{gid:[0-9]+}/player/{pid:[0-9]+}/ledger.{format:csv|json}", h.getLedger},
+		route{"GET", "/game/{gid:[0-9]+}/map/", h.finished(h.getGameMap)},
+		route{"GET", "/game/{gid:[0-9]+}/field/stats", h.finished(h.getFieldStats)},
+		route{"GET", "/game/{gid:[0-9]+}/render/{layer:[a-z]+}.{format:png|svg}", h.getGameRender},
+		route{"GET", "/game/{gid:[0-9]+}/replay", h.finished(h.getReplay)},
+		route{"GET", "/game/{gid:[0-9]+}/replay/{week:[0-9]+}/", h.finished(h.getReplayWeek)},
 	}
 
 	r := mux.NewRouter()
@@ -72,12 +96,131 @@ func (h *handler) newRouter() *mux.Router {
 	return r
 }
 
+// newAdminRouter serves what players mustn't see, alongside expvar and pprof.
+func (h *handler) newAdminRouter() *mux.Router {
+	r := mux.NewRouter()
+	r.StrictSlash(true)
+	r.Methods("DELETE").Path("/game/{gid:[0-9]+}/").HandlerFunc(h.deleteGameID)
+	r.Methods("GET").Path("/game/{gid:[0-9]+}/field/stats").HandlerFunc(h.getFieldStats)
+	r.Methods("GET").Path("/game/{gid:[0-9]+}/render/{layer:[a-z]+}.{format:png|svg}").HandlerFunc(h.getFullGameRender)
+	r.Methods("GET").Path("/game/{gid:[0-9]+}/journal/").HandlerFunc(h.getJournal)
+	r.Methods("GET").Path("/game/{gid:[0-9]+}/journal/{week:[0-9]+}/").HandlerFunc(h.getStandings)
+	r.Methods("GET").Path("/game/{gid:[0-9]+}/replay").HandlerFunc(h.getReplay)
+	r.Methods("GET").Path("/game/{gid:[0-9]+}/replay/{week:[0-9]+}/").HandlerFunc(h.getReplayWeek)
+	r.Methods("GET").Path("/game/{gid:[0-9]+}/map/").HandlerFunc(h.getGameMap)
+
+	// stored maps show where a game played on them has its oil
+	r.Methods("GET").Path("/map/").HandlerFunc(h.getMaps)
+	r.Methods("GET").Path("/map/{name:[A-Za-z0-9_-]+}/").HandlerFunc(h.getMap)
+	r.Methods("PUT").Path("/map/{name:[A-Za-z0-9_-]+}/").HandlerFunc(h.putMap)
+	r.Methods("POST").Path("/map/{name:[A-Za-z0-9_-]+}/").HandlerFunc(h.postMap)
+	r.Methods("GET").Path("/map/{name:[A-Za-z0-9_-]+}/{layer:[a-z]+}.png").HandlerFunc(h.getMapLayer)
+	r.Methods("PUT").Path("/map/{name:[A-Za-z0-9_-]+}/{layer:[a-z]+}.png").HandlerFunc(h.putMapLayer)
+	r.Methods("GET").Path("/map/{name:[A-Za-z0-9_-]+}/render/{layer:[a-z]+}.{format:png|svg}").HandlerFunc(h.getMapRender)
+	r.PathPrefix("/").Handler(http.DefaultServeMux)
+	return r
+}
+
+// minWeek is the shortest week a client may ask for.
+const minWeek = time.Minute
+
+// gameOptions are what a game is created with. An async game's are kept in
+// the archive, so it can be resumed when the server restarts.
+type gameOptions struct {
+	Mode       string `json:"mode"`
+	Generator  string `json:"generator"`
+	Grid       string `json:"grid"`
+	Wrap       bool   `json:"wrap"`
+	Map        string `json:"map"`
+	Week       string `json:"week"`
+	DrillLimit int    `json:"drillLimit"`
+	Webhook    string `json:"webhook"`
+	Reminder   string `json:"reminder"`
+}
+
+// options checks a game's options and returns them as the game's.
+func (h *handler) options(opts gameOptions) ([]game.Option, error) {
+	mode, err := game.ParseMode(opts.Mode)
+	if err != nil {
+		return nil, err
+	}
+	gen, err := game.ParseGenerator(opts.Generator)
+	if err != nil {
+		return nil, err
+	}
+	grid, err := game.ParseGrid(opts.Grid)
+	if err != nil {
+		return nil, err
+	}
+	topo := game.Topology{Grid: grid, Wrap: opts.Wrap}
+	gameOpts := []game.Option{game.WithMode(mode), game.WithGenerator(gen), game.WithTopology(topo)}
+	if opts.Map != "" {
+		m, ok := h.maps.Get(opts.Map)
+		if !ok {
+			return nil, errors.New("map not found")
+		}
+		gameOpts = append(gameOpts, game.WithMap(m))
+	}
+	if opts.Week != "" {
+		week, err := time.ParseDuration(opts.Week)
+		if err != nil || week < minWeek {
+			return nil, errors.New("invalid week length; at least " + minWeek.String())
+		}
+		gameOpts = append(gameOpts, game.WithWeekLength(week))
+	}
+	if opts.DrillLimit > 0 {
+		gameOpts = append(gameOpts, game.WithDrillLimit(opts.DrillLimit))
+	}
+	if opts.Webhook != "" {
+		hook, err := game.ParseWebhook(opts.Webhook)
+		if err != nil {
+			return nil, err
+		}
+		gameOpts = append(gameOpts, game.WithNotifier(hook))
+	}
+	if opts.Reminder != "" {
+		reminder, err := time.ParseDuration(opts.Reminder)
+		if err != nil {
+			return nil, errors.New("invalid reminder")
+		}
+		gameOpts = append(gameOpts, game.WithReminder(reminder))
+	}
+	return gameOpts, nil
+}
+
 // create game
 func (h *handler) postGame(w http.ResponseWriter, r *http.Request) {
-	gameID := len(h.games)
+	var opts gameOptions
+	decoder := json.NewDecoder(r.Body)
+	if err := decoder.Decode(&opts); err != nil && err != io.EOF {
+		http.Error(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+	gameOpts, err := h.options(opts)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
 
-	g := game.New()
-	h.games = append(h.games, g)
+	// the game's ID is taken before it starts, so an async game's journal
+	// can be written from its first entry
+	h.Lock()
+	gameID := len(h.games)
+	h.games = append(h.games, nil)
+	h.Unlock()
+	if mode, _ := game.ParseMode(opts.Mode); mode == game.Async {
+		journal, err := h.archive.Journal(gameID, opts)
+		if err != nil {
+			log.Printf("journaling game %d: %s", gameID, err)
+		} else if journal != nil {
+			gameOpts = append(gameOpts, game.WithJournal(journal))
+		}
+	}
+	g := game.New(gameOpts...)
+	h.Lock()
+	h.games[gameID] = g
+	h.Unlock()
+	go h.putAway(gameID, g)
 
 	if _, err := w.Write([]byte(fmt.Sprintf("%d", gameID))); err != nil {
 		w.WriteHeader(http.StatusInternalServerError)
@@ -94,7 +237,11 @@ func (h *handler) postGameID(w http.ResponseWriter, r *http.Request) {
 		panic(err)
 	}
 
-	g := h.games[gameID]
+	g, ok := h.game(gameID)
+	if !ok {
+		http.Error(w, "game not found", http.StatusNotFound)
+		return
+	}
 
 	var name string
 	decoder := json.NewDecoder(r.Body)
@@ -104,7 +251,13 @@ func (h *handler) postGameID(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	playerID := g.Join(name)
+	ctx, cancel := context.WithTimeout(r.Context(), *timeout)
+	defer cancel()
+	playerID, err := g.Join(ctx, name)
+	if err != nil {
+		gameError(w, err)
+		return
+	}
 	if _, err := w.Write([]byte(fmt.Sprintf("%d", playerID))); err != nil {
 		w.WriteHeader(http.StatusInternalServerError)
 		return
@@ -119,7 +272,18 @@ func (h *handler) getGameID(w http.ResponseWriter, r *http.Request) {
 		// mux should guarantee a parsable int
 		panic(err)
 	}
-	update := h.games[gameID].Status()
+	g, ok := h.game(gameID)
+	if !ok {
+		http.Error(w, "game not found", http.StatusNotFound)
+		return
+	}
+	ctx, cancel := context.WithTimeout(r.Context(), *timeout)
+	defer cancel()
+	update, err := g.Status(ctx)
+	if err != nil {
+		gameError(w, err)
+		return
+	}
 	js, err := json.Marshal(update)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
@@ -129,6 +293,21 @@ func (h *handler) getGameID(w http.ResponseWriter, r *http.Request) {
 	w.Write(js)
 }
 
+// analyze a game's field for balancing
+func (h *handler) getFieldStats(w http.ResponseWriter, r *http.Request) {
+	gameID, err := strconv.Atoi(mux.Vars(r)["gid"])
+	if err != nil {
+		// mux should guarantee a parsable int
+		panic(err)
+	}
+	g, ok := h.game(gameID)
+	if !ok {
+		http.Error(w, "game not found", http.StatusNotFound)
+		return
+	}
+	writeJSON(w, g.FieldStats())
+}
+
 // move making... starting, surveying, drilling, selling, scoring
 func (h *handler) postPlayerID(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
@@ -149,8 +328,18 @@ func (h *handler) postPlayerID(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	g := h.games[gameID]
-	update := g.Move(playerID, mv)
+	g, ok := h.game(gameID)
+	if !ok {
+		http.Error(w, "game not found", http.StatusNotFound)
+		return
+	}
+	ctx, cancel := context.WithTimeout(r.Context(), *timeout)
+	defer cancel()
+	update, err := g.Move(ctx, playerID, mv)
+	if err != nil {
+		gameError(w, err)
+		return
+	}
 
 	js, err := json.Marshal(update)
 	if err != nil {
@@ -172,13 +361,19 @@ func (h *handler) getPlayerID(w http.ResponseWriter, r *http.Request) {
 		panic(err)
 	}
 
-	g := h.games[gameID]
-	if g == nil {
-		http.Error(w, "game not found", http.StatusBadRequest)
+	g, ok := h.game(gameID)
+	if !ok {
+		http.Error(w, "game not found", http.StatusNotFound)
 		return
 	}
 
-	state := g.View(playerID)
+	ctx, cancel := context.WithTimeout(r.Context(), *timeout)
+	defer cancel()
+	state, err := g.View(ctx, playerID)
+	if err != nil {
+		gameError(w, err)
+		return
+	}
 
 	js, err := json.Marshal(state)
 	if err != nil {
@@ -189,6 +384,24 @@ func (h *handler) getPlayerID(w http.ResponseWriter, r *http.Request) {
 	w.Write(js)
 }
 
+// gameError reports a game that couldn't answer a request.
+func gameError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, game.ErrNoPlayer):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case errors.Is(err, game.ErrClosed):
+		http.Error(w, err.Error(), http.StatusGone)
+	case errors.Is(err, game.ErrNotYourTurn):
+		http.Error(w, err.Error(), http.StatusConflict)
+	case errors.Is(err, context.DeadlineExceeded):
+		stats.Add("TimedOut", 1)
+		http.Error(w, "game busy; try again", http.StatusServiceUnavailable)
+	default:
+		// the client went away
+		http.Error(w, err.Error(), http.StatusServiceUnavailable)
+	}
+}
+
 func publishRuntime() {
 	expvar.Publish("NumGoroutine", expvar.Func(
 		func() interface{} { return runtime.NumGoroutine() },
@@ -0,0 +1,202 @@
+package main
+
+import (
+	"expvar"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestArchive(t *testing.T) {
+	if expvar.Get("NumGoroutine") == nil {
+		publishRuntime()
+	}
+	numGoroutine := func() int {
+		return expvar.Get("NumGoroutine").(expvar.Func)().(int)
+	}
+	baseline := numGoroutine()
+
+	maps, _ := newMapStore("")
+	h := &handler{maps: maps, archive: newArchive(t.TempDir())}
+	public, admin := h.newRouter(), h.newAdminRouter()
+	do := func(router http.Handler, method, path, body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(method, path, strings.NewReader(body)))
+		return w
+	}
+
+	do(public, "POST", "/game/", "")
+	player := "/game/0/player/" + do(public, "POST", "/game/0/", `"bob"`).Body.String() + "/"
+	do(public, "POST", player, "-1")
+	if w := do(public, "POST", player, "2"); w.Code != http.StatusOK {
+		t.Fatalf("surveying -> %d %s", w.Code, w.Body)
+	}
+
+	// the replay shows where the oil is, so players wait for the game to end
+	if w := do(public, "GET", "/game/0/replay", ""); w.Code != http.StatusForbidden {
+		t.Errorf("replay of a game in play -> %d; expect %d", w.Code, http.StatusForbidden)
+	}
+	if w := do(public, "GET", "/game/0/field/stats", ""); w.Code != http.StatusForbidden {
+		t.Errorf("field stats of a game in play -> %d; expect %d", w.Code, http.StatusForbidden)
+	}
+	if w := do(public, "GET", "/game/0/map/", ""); w.Code != http.StatusForbidden {
+		t.Errorf("map of a game in play -> %d; expect %d", w.Code, http.StatusForbidden)
+	}
+	if w := do(admin, "GET", "/game/0/map/", ""); w.Code != http.StatusOK {
+		t.Errorf("admin map of a game in play -> %d %s", w.Code, w.Body)
+	}
+	if w := do(public, "GET", "/map/", ""); w.Code != http.StatusNotFound {
+		t.Errorf("public list of maps -> %d; expect %d", w.Code, http.StatusNotFound)
+	}
+
+	if w := do(admin, "DELETE", "/game/0/", ""); w.Code != http.StatusNoContent {
+		t.Fatalf("closing the game -> %d %s", w.Code, w.Body)
+	}
+
+	// the game and its archiver are gone, and the journal is on disk
+	deadline := time.Now().Add(time.Second)
+	for numGoroutine() > baseline && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	if n := numGoroutine(); n > baseline {
+		t.Errorf("%d goroutines after archiving; expect %d", n, baseline)
+	}
+	h.RLock()
+	archived := h.games[0] == nil
+	h.RUnlock()
+	if !archived {
+		t.Fatalf("closed game still in memory")
+	}
+	if _, err := os.Stat(h.archive.path(0)); err != nil {
+		t.Fatalf("archived journal: %s", err)
+	}
+
+	// archived games can be looked back on, but not played
+	if w := do(admin, "GET", "/game/0/journal/", ""); w.Code != http.StatusOK || !strings.Contains(w.Body.String(), `"surveyed"`) {
+		t.Errorf("archived journal -> %d %s", w.Code, w.Body)
+	}
+	if w := do(public, "GET", "/game/0/field/stats", ""); w.Code != http.StatusOK || !strings.Contains(w.Body.String(), `"expectedValue"`) {
+		t.Errorf("field stats of a finished game -> %d %s", w.Code, w.Body)
+	}
+	if w := do(public, "GET", "/game/0/map/", ""); w.Code != http.StatusOK {
+		t.Errorf("map of a finished game -> %d %s", w.Code, w.Body)
+	}
+	if w := do(public, "GET", "/game/0/replay/1/", ""); w.Code != http.StatusOK {
+		t.Errorf("replay of a finished game -> %d %s", w.Code, w.Body)
+	}
+	if w := do(public, "GET", player, ""); w.Code != http.StatusGone {
+		t.Errorf("viewing an archived game -> %d; expect %d", w.Code, http.StatusGone)
+	}
+	if w := do(public, "GET", "/game/1/", ""); w.Code != http.StatusNotFound {
+		t.Errorf("status of a missing game -> %d; expect %d", w.Code, http.StatusNotFound)
+	}
+}
+
+func TestResumeAfterRestart(t *testing.T) {
+	dir := t.TempDir()
+	maps, _ := newMapStore("")
+	h := &handler{maps: maps, archive: newArchive(dir)}
+	router := h.newRouter()
+	do := func(router http.Handler, method, path, body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(method, path, strings.NewReader(body)))
+		return w
+	}
+
+	do(router, "POST", "/game/", "")
+	do(router, "POST", "/game/", `{"mode": "async", "week": "1h"}`)
+	do(router, "DELETE", "/game/0/", "")
+	bob := "/game/1/player/" + do(router, "POST", "/game/1/", `"bob"`).Body.String() + "/"
+	do(router, "POST", bob, "-1")
+	if w := do(router, "POST", bob, "2"); w.Code != http.StatusOK {
+		t.Fatalf("surveying -> %d %s", w.Code, w.Body)
+	}
+	defer h.games[1].Close()
+
+	// the server stops, leaving behind what it had written as the game was
+	// played, and restarts
+	restarted := t.TempDir()
+	for _, name := range []string{"game1.journal", "game1.options.json"} {
+		js, err := os.ReadFile(filepath.Join(dir, name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(restarted, name), js, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	h = &handler{maps: maps, archive: newArchive(restarted)}
+	if err := h.resume(); err != nil {
+		t.Fatalf("resume() -> %s", err)
+	}
+	router = h.newRouter()
+	defer h.games[1].Close()
+
+	if w := do(router, "GET", "/game/0/", ""); w.Code != http.StatusNotFound {
+		t.Errorf("status of a game not archived -> %d; expect %d", w.Code, http.StatusNotFound)
+	}
+	if w := do(router, "GET", bob, ""); w.Code != http.StatusOK || !strings.Contains(w.Body.String(), `"wells"`) {
+		t.Errorf("resumed view -> %d %s; expect bob at his wells", w.Code, w.Body)
+	}
+	if w := do(router, "POST", "/game/", ""); w.Body.String() != "2" {
+		t.Errorf("new game after a restart -> %s; expect game 2", w.Body)
+	}
+	defer h.games[2].Close()
+}
+
+func TestForgetting(t *testing.T) {
+	maps, _ := newMapStore("")
+	h := &handler{maps: maps, archive: newArchive("")}
+	public, admin := h.newRouter(), h.newAdminRouter()
+	do := func(router http.Handler, method, path string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(method, path, nil))
+		return w
+	}
+
+	// without an archive directory only the most recent closed games are kept
+	for i := 0; i <= recentGames; i++ {
+		do(public, "POST", "/game/")
+		path := "/game/" + strconv.Itoa(i) + "/"
+		do(admin, "DELETE", path)
+		deadline := time.Now().Add(time.Second)
+		for {
+			h.RLock()
+			dropped := h.games[i] == nil
+			h.RUnlock()
+			if dropped {
+				break
+			}
+			if time.Now().After(deadline) {
+				t.Fatalf("closed game %d still in memory", i)
+			}
+			time.Sleep(time.Millisecond)
+		}
+	}
+	if w := do(admin, "GET", "/game/0/journal/"); w.Code != http.StatusNotFound {
+		t.Errorf("journal of a forgotten game -> %d; expect %d", w.Code, http.StatusNotFound)
+	}
+	if w := do(admin, "GET", "/game/1/journal/"); w.Code != http.StatusOK {
+		t.Errorf("journal of a recent game -> %d; expect %d", w.Code, http.StatusOK)
+	}
+}
+
+func TestShortWeek(t *testing.T) {
+	maps, _ := newMapStore("")
+	h := &handler{maps: maps, archive: newArchive("")}
+	for _, week := range []string{"1ns", "-5m", "59s"} {
+		w := httptest.NewRecorder()
+		h.newRouter().ServeHTTP(w, httptest.NewRequest("POST", "/game/", strings.NewReader(`{"mode": "realtime", "week": "`+week+`"}`)))
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("a %s week -> %d; expect %d", week, w.Code, http.StatusBadRequest)
+		}
+	}
+	if len(h.games) != 0 {
+		t.Errorf("%d games created with short weeks", len(h.games))
+	}
+}
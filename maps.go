@@ -0,0 +1,220 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/9r33n/wildcatting/game"
+	"github.com/gorilla/mux"
+)
+
+// mapStore holds the maps available to new games. When it has a directory,
+// maps are saved there as JSON files and loaded again at startup.
+type mapStore struct {
+	sync.Mutex
+	dir  string
+	maps map[string]game.Map
+}
+
+func newMapStore(dir string) (*mapStore, error) {
+	ms := &mapStore{dir: dir, maps: make(map[string]game.Map)}
+	if dir == "" {
+		return ms, nil
+	}
+
+	paths, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return nil, err
+	}
+	for _, path := range paths {
+		js, err := ioutil.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		var m game.Map
+		if err := json.Unmarshal(js, &m); err != nil {
+			return nil, fmt.Errorf("%s: %s", path, err)
+		}
+		m.Name = strings.TrimSuffix(filepath.Base(path), ".json")
+		if err := m.Validate(); err != nil {
+			return nil, fmt.Errorf("%s: %s", path, err)
+		}
+		ms.maps[m.Name] = m
+	}
+	log.Printf("Loaded %d maps from %s", len(ms.maps), dir)
+	return ms, nil
+}
+
+func (ms *mapStore) Get(name string) (game.Map, bool) {
+	ms.Lock()
+	defer ms.Unlock()
+	m, ok := ms.maps[name]
+	return m, ok
+}
+
+func (ms *mapStore) Names() []string {
+	ms.Lock()
+	defer ms.Unlock()
+	names := make([]string, 0, len(ms.maps))
+	for name := range ms.maps {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Put validates and stores a map, saving it to the store's directory.
+func (ms *mapStore) Put(m game.Map) error {
+	if err := m.Validate(); err != nil {
+		return err
+	}
+
+	ms.Lock()
+	defer ms.Unlock()
+	if ms.dir != "" {
+		js, err := json.Marshal(m)
+		if err != nil {
+			return err
+		}
+		path := filepath.Join(ms.dir, m.Name+".json")
+		if err := ioutil.WriteFile(path+".tmp", js, 0644); err != nil {
+			return err
+		}
+		if err := os.Rename(path+".tmp", path); err != nil {
+			return err
+		}
+	}
+	ms.maps[m.Name] = m
+	return nil
+}
+
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	js, err := json.Marshal(v)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(js)
+}
+
+// list maps
+func (h *handler) getMaps(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, h.maps.Names())
+}
+
+func (h *handler) getMap(w http.ResponseWriter, r *http.Request) {
+	m, ok := h.maps.Get(mux.Vars(r)["name"])
+	if !ok {
+		http.Error(w, "map not found", http.StatusNotFound)
+		return
+	}
+	writeJSON(w, m)
+}
+
+// upload a map, replacing any map with the same name
+func (h *handler) putMap(w http.ResponseWriter, r *http.Request) {
+	var m game.Map
+	decoder := json.NewDecoder(r.Body)
+	if err := decoder.Decode(&m); err != nil {
+		http.Error(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+	m.Name = mux.Vars(r)["name"]
+
+	if err := h.maps.Put(m); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	log.Printf("Stored map %s", m.Name)
+}
+
+// edit regions of a map
+func (h *handler) postMap(w http.ResponseWriter, r *http.Request) {
+	name := mux.Vars(r)["name"]
+	m, ok := h.maps.Get(name)
+	if !ok {
+		http.Error(w, "map not found", http.StatusNotFound)
+		return
+	}
+
+	var edits []game.Edit
+	decoder := json.NewDecoder(r.Body)
+	if err := decoder.Decode(&edits); err != nil {
+		http.Error(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	// edit a copy so a bad edit leaves the stored map alone
+	edited := m.Copy()
+	for _, e := range edits {
+		if err := edited.Apply(e); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+	if err := h.maps.Put(edited); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	log.Printf("Edited map %s with %d edits", name, len(edits))
+	writeJSON(w, edited)
+}
+
+func (h *handler) getMapLayer(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	m, ok := h.maps.Get(vars["name"])
+	if !ok {
+		http.Error(w, "map not found", http.StatusNotFound)
+		return
+	}
+	w.Header().Set("Content-Type", "image/png")
+	if err := m.EncodeLayer(w, vars["layer"]); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	}
+}
+
+// replace one layer of a map with a PNG
+func (h *handler) putMapLayer(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	m, ok := h.maps.Get(vars["name"])
+	if !ok {
+		http.Error(w, "map not found", http.StatusNotFound)
+		return
+	}
+	if err := m.DecodeLayer(r.Body, vars["layer"]); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err := h.maps.Put(m); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	log.Printf("Replaced %s layer of map %s", vars["layer"], m.Name)
+}
+
+// export a game's field as a map
+func (h *handler) getGameMap(w http.ResponseWriter, r *http.Request) {
+	gameID, err := strconv.Atoi(mux.Vars(r)["gid"])
+	if err != nil {
+		// mux should guarantee a parsable int
+		panic(err)
+	}
+	g, ok := h.game(gameID)
+	if !ok {
+		http.Error(w, "game not found", http.StatusNotFound)
+		return
+	}
+	m := g.Map()
+	m.Name = fmt.Sprintf("game%d", gameID)
+	writeJSON(w, m)
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/9r33n/wildcatting/game"
+	"github.com/gorilla/mux"
+)
+
+// renderOptions reads what to render from the route and query string.
+func renderOptions(r *http.Request) (game.RenderOptions, error) {
+	vars := mux.Vars(r)
+	opts := game.RenderOptions{Layer: vars["layer"], Format: vars["format"]}
+
+	query := r.URL.Query()
+	if s := query.Get("scale"); s != "" {
+		scale, err := strconv.Atoi(s)
+		if err != nil {
+			return opts, err
+		}
+		if scale < 1 || scale > game.MaxScale {
+			return opts, fmt.Errorf("scale must be 1 to %d", game.MaxScale)
+		}
+		opts.Scale = scale
+	}
+	if s := query.Get("wells"); s != "" {
+		wells, err := strconv.ParseBool(s)
+		if err != nil {
+			return opts, err
+		}
+		opts.Wells = wells
+	}
+	return opts, nil
+}
+
+func setImageType(w http.ResponseWriter, format string) {
+	if format == "svg" {
+		w.Header().Set("Content-Type", "image/svg+xml")
+		return
+	}
+	w.Header().Set("Content-Type", "image/png")
+}
+
+// render a layer of a game's field, with its wells, showing only the oil and
+// gas players have found
+func (h *handler) getGameRender(w http.ResponseWriter, r *http.Request) {
+	h.renderGame(w, r, true)
+}
+
+// render a layer of a game's field, with its wells and all its oil and gas
+func (h *handler) getFullGameRender(w http.ResponseWriter, r *http.Request) {
+	h.renderGame(w, r, false)
+}
+
+func (h *handler) renderGame(w http.ResponseWriter, r *http.Request, revealed bool) {
+	gameID, err := strconv.Atoi(mux.Vars(r)["gid"])
+	if err != nil {
+		// mux should guarantee a parsable int
+		panic(err)
+	}
+	g, ok := h.game(gameID)
+	if !ok {
+		http.Error(w, "game not found", http.StatusNotFound)
+		return
+	}
+	opts, err := renderOptions(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	opts.Revealed = revealed
+
+	setImageType(w, opts.Format)
+	if err := g.Render(w, opts); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	}
+}
+
+// render a layer of a stored map
+func (h *handler) getMapRender(w http.ResponseWriter, r *http.Request) {
+	m, ok := h.maps.Get(mux.Vars(r)["name"])
+	if !ok {
+		http.Error(w, "map not found", http.StatusNotFound)
+		return
+	}
+	opts, err := renderOptions(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	setImageType(w, opts.Format)
+	if err := game.RenderMap(w, m, opts); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	}
+}